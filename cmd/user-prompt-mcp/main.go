@@ -1,49 +1,154 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"log"
+	"log/slog"
+	"os"
 	"time"
 
+	"github.com/nazar256/user-prompt-mcp/internal/config"
+	"github.com/nazar256/user-prompt-mcp/internal/logging"
 	"github.com/nazar256/user-prompt-mcp/internal/server"
+	"github.com/nazar256/user-prompt-mcp/internal/tracing"
 	"github.com/nazar256/user-prompt-mcp/pkg/gui"
 	"github.com/nazar256/user-prompt-mcp/pkg/prompt"
 )
 
-const defaultPromptServerURL = "http://localhost:3030"
+const (
+	defaultServerPort      = "3030"
+	defaultPromptServerURL = "http://localhost:" + defaultServerPort
+)
 
 func main() {
-	log.SetPrefix("[UserPromptClient] ")
-	log.SetFlags(log.LstdFlags | log.Lshortfile | log.Lmicroseconds)
-	log.Println("----------------------------------------------------")
-	log.Println("Starting User Prompt MCP Client...")
-
 	timeoutSeconds := flag.Int("timeout", 0, "Default timeout in seconds for user input (default: 1200 from prompt.Service)")
 	promptServerURL := flag.String("prompt-server-url", defaultPromptServerURL, "URL of the user-prompt-server")
-	flag.Parse()
+	provider := flag.String("provider", "remote", "Dialog provider: 'remote' (user-prompt-server), 'elicitation' (the MCP client's own input UI, or user-prompt-server if the client does not support it), 'exec' (run --exec-command), 'spool' (files in --spool-dir) or 'script' (replay --script-file)")
+	execCommand := flag.String("exec-command", "", "Shell command that shows the prompt and prints the answer to stdout (for --provider exec)")
+	spoolDir := flag.String("spool-dir", "", "Directory where prompt files are written and answer files are read (for --provider spool)")
+	scriptFile := flag.String("script-file", "", "YAML or JSON script of expected prompts and canned answers (for --provider script)")
+	recordFile := flag.String("record", "", "Append every prompt, answer and its timing as a JSON line to this file (replayable with --provider script --script-file)")
+	noUIPolicy := flag.String("no-ui-policy", "", "What user-prompt-server does when no UI is connected: 'wait' for the prompt timeout or 'fail' after --no-ui-grace (default: the server's setting)")
+	noUIGraceSeconds := flag.Int("no-ui-grace", 0, "Seconds user-prompt-server waits for a UI to connect before failing, with --no-ui-policy fail")
+	fallbackProvider := flag.String("fallback-provider", "", "Provider used when user-prompt-server reports that no UI is connected (exec, spool or script); implies --no-ui-policy fail")
+	maxQueue := flag.Int("max-queue", 10, "Maximum number of prompts waiting while another is shown (0 for unlimited)")
+	maxConcurrent := flag.Int("max-concurrent", 1, "Maximum number of prompts shown at once, if the provider supports concurrent prompts (exec, spool)")
+	autoStartServer := flag.Bool("auto-start-server", false, "Start user-prompt-server in the background when it is not running at --prompt-server-url (for --provider remote); clients share one server")
+	serverCommand := flag.String("server-command", "", "user-prompt-server executable for --auto-start-server (default: next to this executable or in PATH)")
+	serverIdleMinutes := flag.Int("server-idle-shutdown", 30, "Minutes without prompts after which an auto-started server shuts down (0 keeps it running)")
+	progressIntervalSeconds := flag.Int("progress-interval", 10, "Seconds between MCP progress notifications while waiting for the user, for clients that request them")
+	// stdout carries the MCP protocol and MCP clients rarely show stderr,
+	// so log to a file by default.
+	logOpts := logging.DefaultOptions(logging.DefaultFile("user-prompt-mcp"))
+	logging.RegisterFlags(flag.CommandLine, &logOpts)
+	traceOpts := tracing.Options{ServiceName: "user-prompt-mcp"}
+	tracing.RegisterFlags(flag.CommandLine, &traceOpts)
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], config.Options{Section: "mcp"})
+	if err != nil {
+		logging.Fatal("Failed to load configuration", "error", err)
+	}
+	if cfg.PrintRequested {
+		cfg.Print(os.Stdout)
+		return
+	}
+	logFile, err := logging.Setup(logOpts)
+	if err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+	defer logFile.Close()
+	traceShutdown, err := tracing.Setup(context.Background(), traceOpts)
+	if err != nil {
+		logging.Fatal("Invalid tracing configuration", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := traceShutdown(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+	slog.Info("Starting User Prompt MCP Client", "pid", os.Getpid())
+	if cfg.File != "" {
+		slog.Info("Loaded configuration", "file", cfg.File)
+	}
 
 	opts := prompt.DefaultOptions()
 	if *timeoutSeconds > 0 {
 		opts.Timeout = time.Duration(*timeoutSeconds) * time.Second
 	}
+	opts.MaxQueueLength = *maxQueue
+	opts.MaxConcurrent = *maxConcurrent
 
-	log.Printf("Configuring to use remote prompt server at: %s", *promptServerURL)
-	opts.Dialog = gui.NewRemoteDialog(*promptServerURL)
+	providerCfg := providerConfig{
+		promptServerURL: *promptServerURL,
+		noUIPolicy:      *noUIPolicy,
+		noUIGraceMs:     int64(*noUIGraceSeconds) * 1000,
+		execCommand:     *execCommand,
+		spoolDir:        *spoolDir,
+
+		autoStart:         *autoStartServer,
+		serverCommand:     *serverCommand,
+		serverIdleMinutes: *serverIdleMinutes,
+	}
+	if *fallbackProvider != "" && providerCfg.noUIPolicy == "" {
+		providerCfg.noUIPolicy = "fail" // Waiting for a UI would never reach the fallback
+	}
+	usesScript := *provider == gui.ProviderScript || *fallbackProvider == gui.ProviderScript
+	if usesScript && *scriptFile != "" {
+		slog.Info("Loading prompt script", "path", *scriptFile)
+		providerCfg.scriptDialog, err = gui.NewScriptDialogFromFile(*scriptFile)
+		if err != nil {
+			logging.Fatal("Failed to load prompt script", "path", *scriptFile, "error", err)
+		}
+	}
+
+	dialog, err := newDialogProvider(*provider, providerCfg)
+	if err != nil {
+		logging.Fatal("Failed to configure dialog provider", "error", err)
+	}
+	if *fallbackProvider != "" {
+		fallback, err := newDialogProvider(*fallbackProvider, providerCfg)
+		if err != nil {
+			logging.Fatal("Failed to configure fallback dialog provider", "error", err)
+		}
+		slog.Info("Falling back to another provider when no UI is connected", "fallback_provider", *fallbackProvider)
+		dialog = gui.NewFallbackDialog(dialog, fallback)
+	}
+	opts.Dialog = dialog
+	if *recordFile != "" {
+		slog.Info("Recording prompts", "path", *recordFile)
+		opts.Dialog = gui.NewRecordingDialog(opts.Dialog, *recordFile)
+	}
 
 	if err := opts.Dialog.CheckDependencies(); err != nil {
-		log.Fatalf("Remote dialog dependency check failed: %v", err)
+		logging.Fatal("Dialog provider dependency check failed", "provider", *provider, "error", err)
 	}
-	log.Println("Using RemoteDialog provider.")
+	slog.Info("Using dialog provider", "provider", *provider)
 
 	promptService := prompt.NewService(opts)
-	log.Printf("Prompt service initialized with default timeout: %v", opts.Timeout)
+	slog.Info("Prompt service initialized", "default_timeout", opts.Timeout)
 
 	mcpServer := server.NewMCPServer(promptService)
+	if *progressIntervalSeconds > 0 {
+		mcpServer.SetProgressInterval(time.Duration(*progressIntervalSeconds) * time.Second)
+	}
 	mcpServer.RegisterUserPromptTool()
+	mcpServer.RegisterPromptResources()
+	mcpServer.RegisterPromptTemplates()
 
-	log.Println("MCP Client (stdio server) starting. Waiting for stdio requests from Cursor...")
-	if err := mcpServer.ServeStdio(); err != nil {
-		log.Fatalf("MCP Client (stdio server) error: %v", err)
+	slog.Info("MCP Client (stdio server) starting, waiting for stdio requests")
+	serveErr := mcpServer.ServeStdio()
+	// Report the script's result before exiting either way; the script may
+	// be the primary or the fallback provider.
+	if providerCfg.scriptDialog != nil {
+		if err := providerCfg.scriptDialog.Err(); err != nil {
+			slog.Error("Prompt script did not run as expected", "error", err)
+		} else {
+			slog.Info("Prompt script completed")
+		}
+	}
+	if serveErr != nil {
+		logging.Fatal("MCP Client (stdio server) failed", "error", serveErr)
 	}
-	log.Println("MCP Client (stdio server) finished.")
+	slog.Info("MCP Client (stdio server) finished")
 }
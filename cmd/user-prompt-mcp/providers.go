@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/server"
+	"github.com/nazar256/user-prompt-mcp/pkg/gui"
+)
+
+// providerConfig holds the provider-specific flags.
+type providerConfig struct {
+	promptServerURL string
+	noUIPolicy      string
+	noUIGraceMs     int64
+	execCommand     string
+	spoolDir        string
+	// scriptDialog replays the script file; it is loaded once and shared
+	// if the script provider is also the fallback.
+	scriptDialog *gui.ScriptDialog
+	// autoStart starts user-prompt-server with serverCommand when it is
+	// not running; it shuts down after serverIdleMinutes without prompts.
+	autoStart         bool
+	serverCommand     string
+	serverIdleMinutes int
+}
+
+// newDialogProvider creates the dialog provider with the given name.
+func newDialogProvider(name string, cfg providerConfig) (gui.DialogProvider, error) {
+	switch name {
+	case gui.ProviderRemote:
+		slog.Info("Configuring to use remote prompt server", "url", cfg.promptServerURL)
+		remote := gui.NewRemoteDialog(cfg.promptServerURL)
+		remote.NoUIPolicy = cfg.noUIPolicy
+		remote.NoUIGraceMs = cfg.noUIGraceMs
+		if cfg.autoStart {
+			launcher, err := newServerLauncher(cfg)
+			if err != nil {
+				return nil, err
+			}
+			slog.Info("Starting the prompt server when it is not running", "command", launcher.Command, "args", launcher.Args)
+			remote.Launcher = launcher
+			// Every client shares the started server, which shows one
+			// prompt at a time, so wait for other clients' prompts.
+			remote.ConflictRetryInterval = time.Second
+		}
+		return remote, nil
+	case server.ElicitationProvider:
+		remote, err := newDialogProvider(gui.ProviderRemote, cfg)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Configuring to ask the MCP client via elicitation, falling back to the remote prompt server")
+		return server.NewElicitationDialog(remote), nil
+	case gui.ProviderExec:
+		slog.Info("Configuring to use prompt command", "command", cfg.execCommand)
+		return gui.NewExecDialog(cfg.execCommand), nil
+	case gui.ProviderSpool:
+		slog.Info("Configuring to use spool directory", "dir", cfg.spoolDir)
+		return gui.NewSpoolDialog(cfg.spoolDir), nil
+	case gui.ProviderScript:
+		if cfg.scriptDialog == nil {
+			return nil, errors.New("the script provider requires --script-file")
+		}
+		return cfg.scriptDialog, nil
+	default:
+		return nil, fmt.Errorf("unknown dialog provider %q", name)
+	}
+}
+
+// newServerLauncher creates the launcher that starts user-prompt-server for
+// cfg.promptServerURL. Only servers on this machine can be started.
+func newServerLauncher(cfg providerConfig) (*gui.ServerLauncher, error) {
+	var args []string
+	if strings.HasPrefix(cfg.promptServerURL, "unix:") {
+		// Keep a loopback listener for the Vibeframe page, which browsers
+		// cannot open over a socket.
+		args = []string{"--listen", cfg.promptServerURL, "--listen", "localhost:" + defaultServerPort}
+	} else {
+		u, err := url.Parse(cfg.promptServerURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt server URL: %w", err)
+		}
+		if u.Scheme != "http" {
+			return nil, fmt.Errorf("cannot auto-start a server for %s: only http:// and unix:// URLs are supported", cfg.promptServerURL)
+		}
+		switch u.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return nil, fmt.Errorf("cannot auto-start a server on %s: it is not on this machine", u.Host)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+		}
+		args = []string{"--listen", net.JoinHostPort(u.Hostname(), port)}
+	}
+	args = append(args, "--idle-shutdown", strconv.Itoa(cfg.serverIdleMinutes))
+
+	stateDir, err := gui.DefaultLauncherStateDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a directory for the server's state: %w", err)
+	}
+	command := cfg.serverCommand
+	if command == "" {
+		command = findServerCommand()
+	}
+	return gui.NewServerLauncher(command, args, stateDir), nil
+}
+
+// findServerCommand returns user-prompt-server next to this executable, as
+// installed by the install script, or else looks it up in PATH.
+func findServerCommand() string {
+	name := "user-prompt-server"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if exe, err := os.Executable(); err == nil {
+		sibling := filepath.Join(filepath.Dir(exe), name)
+		if _, err := os.Stat(sibling); err == nil {
+			return sibling
+		}
+	}
+	return name
+}
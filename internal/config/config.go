@@ -0,0 +1,268 @@
+// Package config layers the settings of user-prompt-mcp and
+// user-prompt-server: built-in flag defaults, a config file, environment
+// variables and command-line flags, each overriding the previous one.
+//
+// Every setting is a command-line flag. The config file uses the flag names
+// as keys under a section per binary, and the environment variable of a flag
+// is USER_PROMPT_ followed by its name in upper case with dashes replaced by
+// underscores, e.g. USER_PROMPT_TIMEOUT for --timeout.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables of all settings.
+const EnvPrefix = "USER_PROMPT_"
+
+// ConfigEnv names the config file, overriding the default location.
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+// fileNames are the config files looked up in DefaultDir, in order.
+var fileNames = []string{"config.toml", "config.yaml", "config.yml", "config.json"}
+
+// Where the effective value of a setting comes from.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Flags added to the flag set by Load.
+const (
+	configFlag      = "config"
+	printConfigFlag = "print-config"
+)
+
+// Options configures Load.
+type Options struct {
+	// Section is the config file section holding this binary's settings,
+	// e.g. "server".
+	Section string
+	// Secrets are the settings whose values Print redacts.
+	Secrets []string
+}
+
+// Setting is the effective value of one flag.
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+	Origin string // The file or environment variable the value was read from
+}
+
+// Config is the result of Load.
+type Config struct {
+	// File is the config file that was read, empty if there was none.
+	File string
+	// Settings are all settings in flag name order.
+	Settings []Setting
+	// PrintRequested is set when --print-config was given; the caller
+	// should Print the configuration and exit.
+	PrintRequested bool
+
+	secrets map[string]bool
+}
+
+// DefaultDir returns the directory searched for a config file:
+// user-prompt-mcp in the user's config directory ($XDG_CONFIG_HOME or
+// ~/.config on Linux).
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "user-prompt-mcp"), nil
+}
+
+// EnvName returns the environment variable for the setting name.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load adds the --config and --print-config flags to fs, parses args, and
+// then fills every flag not given on the command line from its environment
+// variable or, failing that, from the config file.
+func Load(fs *flag.FlagSet, args []string, opts Options) (*Config, error) {
+	configPath := fs.String(configFlag, "", "Config file (default: $"+ConfigEnv+" or config.{toml,yaml,yml,json} in the user-prompt-mcp user config directory)")
+	printConfig := fs.Bool(printConfigFlag, false, "Print the effective configuration and where each value comes from, then exit")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{PrintRequested: *printConfig, secrets: make(map[string]bool)}
+	for _, name := range opts.Secrets {
+		cfg.secrets[name] = true
+	}
+
+	path, pathSource := *configPath, SourceFlag
+	if path == "" {
+		path, pathSource = os.Getenv(ConfigEnv), SourceEnv
+	}
+	explicit := path != ""
+	if !explicit {
+		path, pathSource = findDefaultFile(), SourceDefault
+	}
+	var fileValues map[string]string
+	if path != "" {
+		values, err := readFile(path, opts.Section)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		case err != nil:
+			return nil, err
+		default:
+			cfg.File = path
+			fileValues = values
+		}
+	}
+
+	setOnCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setOnCommandLine[f.Name] = true })
+
+	for name := range fileValues {
+		if fs.Lookup(name) == nil || name == configFlag || name == printConfigFlag {
+			return nil, fmt.Errorf("%s: unknown setting %q in section %q", cfg.File, name, opts.Section)
+		}
+	}
+
+	var loadErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if loadErr != nil {
+			return
+		}
+		setting := Setting{Name: f.Name, Source: SourceDefault}
+		envName := EnvName(f.Name)
+		fileValue, inFile := fileValues[f.Name]
+		envValue, inEnv := os.LookupEnv(envName)
+
+		switch {
+		case f.Name == printConfigFlag:
+		case setOnCommandLine[f.Name]:
+			setting.Source = SourceFlag
+		case f.Name == configFlag:
+			f.Value.Set(cfg.File)
+			if cfg.File != "" && pathSource == SourceEnv {
+				setting.Source, setting.Origin = SourceEnv, ConfigEnv
+			}
+		case inEnv:
+			if err := f.Value.Set(envValue); err != nil {
+				loadErr = fmt.Errorf("invalid value %q for %s in $%s: %w", envValue, f.Name, envName, err)
+				return
+			}
+			setting.Source, setting.Origin = SourceEnv, envName
+		case inFile:
+			if err := f.Value.Set(fileValue); err != nil {
+				loadErr = fmt.Errorf("invalid value %q for %s in %s: %w", fileValue, f.Name, cfg.File, err)
+				return
+			}
+			setting.Source, setting.Origin = SourceFile, cfg.File
+		}
+		setting.Value = f.Value.String()
+		cfg.Settings = append(cfg.Settings, setting)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return cfg, nil
+}
+
+func findDefaultFile() string {
+	dir, err := DefaultDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range fileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// readFile returns the settings in the given section of a TOML, YAML or JSON
+// config file, as flag values.
+func readFile(path, section string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: unsupported config file format %q, expected .toml, .yaml, .yml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	raw, ok := doc[section]
+	if !ok {
+		return nil, nil
+	}
+	entries, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: section %q must be a table of settings", path, section)
+	}
+	values := make(map[string]string, len(entries))
+	for name, value := range entries {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case int, int64, uint64, float64:
+			values[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s: setting %q in section %q must be a string, number or boolean", path, name, section)
+		}
+	}
+	return values, nil
+}
+
+// Print writes the effective settings and their sources, with secrets
+// redacted.
+func (c *Config) Print(w io.Writer) {
+	if c.File != "" {
+		fmt.Fprintf(w, "# Config file: %s\n", c.File)
+	} else {
+		fmt.Fprintln(w, "# Config file: none")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range c.Settings {
+		if s.Name == printConfigFlag {
+			continue
+		}
+		value := strconv.Quote(s.Value)
+		if c.secrets[s.Name] && s.Value != "" {
+			value = "<redacted>"
+		}
+		source := s.Source
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, value, source)
+	}
+	tw.Flush()
+}
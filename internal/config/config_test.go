@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFlags returns a flag set like the binaries'.
+func testFlags() (*flag.FlagSet, map[string]*string) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	values := map[string]*string{
+		"port":      fs.String("port", "3030", ""),
+		"timeout":   fs.String("timeout", "0", ""),
+		"provider":  fs.String("provider", "remote", ""),
+		"api-token": fs.String("api-token", "", ""),
+	}
+	return fs, values
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func sourceOf(cfg *Config, name string) string {
+	for _, s := range cfg.Settings {
+		if s.Name == name {
+			return s.Source
+		}
+	}
+	return ""
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := writeConfig(t, "config.toml", `
+[server]
+port = 4000
+timeout = 10
+provider = "exec"
+
+[mcp]
+provider = "spool"
+`)
+	t.Setenv(EnvName("timeout"), "20")
+	t.Setenv(EnvName("provider"), "script")
+
+	fs, values := testFlags()
+	cfg, err := Load(fs, []string{"--config", path, "--provider", "remote"}, Options{Section: "server"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		name, value, source string
+	}{
+		{"port", "4000", SourceFile},
+		{"timeout", "20", SourceEnv},
+		{"provider", "remote", SourceFlag},
+		{"api-token", "", SourceDefault},
+	}
+	for _, tt := range tests {
+		if got := *values[tt.name]; got != tt.value {
+			t.Errorf("Expected %s=%q, got %q", tt.name, tt.value, got)
+		}
+		if got := sourceOf(cfg, tt.name); got != tt.source {
+			t.Errorf("Expected %s to come from %s, got %s", tt.name, tt.source, got)
+		}
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	files := map[string]string{
+		"config.yaml": "server:\n  port: 4001\n",
+		"config.json": `{"server": {"port": 4001}}`,
+		"config.toml": "[server]\nport = 4001\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(ConfigEnv, writeConfig(t, name, content))
+			fs, values := testFlags()
+			if _, err := Load(fs, nil, Options{Section: "server"}); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if *values["port"] != "4001" {
+				t.Errorf("Expected port 4001, got %q", *values["port"])
+			}
+		})
+	}
+}
+
+func TestLoadDefaultLocation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "user-prompt-mcp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "user-prompt-mcp", "config.yaml")
+	if err := os.WriteFile(path, []byte("mcp:\n  timeout: 30\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, values := testFlags()
+	cfg, err := Load(fs, nil, Options{Section: "mcp"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.File != path || *values["timeout"] != "30" {
+		t.Errorf("Expected timeout 30 from %s, got %q from %q", path, *values["timeout"], cfg.File)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tests := map[string][]string{
+		"unknown setting": {"--config", writeConfig(t, "config.toml", "[server]\nbogus = 1\n")},
+		"invalid format":  {"--config", writeConfig(t, "config.ini", "port=1")},
+		"missing file":    {"--config", filepath.Join(t.TempDir(), "missing.toml")},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs, _ := testFlags()
+			if _, err := Load(fs, args, Options{Section: "server"}); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(EnvName("api-token"), "s3cret")
+
+	fs, _ := testFlags()
+	cfg, err := Load(fs, []string{"--print-config"}, Options{Section: "server", Secrets: []string{"api-token"}})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.PrintRequested {
+		t.Error("Expected PrintRequested to be set")
+	}
+
+	var out bytes.Buffer
+	cfg.Print(&out)
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("Expected the API token to be redacted, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "env ("+EnvName("api-token")+")") {
+		t.Errorf("Expected the API token source to be shown, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,175 @@
+// Package logging sets up the structured logs of user-prompt-mcp and
+// user-prompt-server on top of log/slog.
+//
+// Prompt content is logged with the Prompt, Title and Answer attributes, so
+// that the handler installed by Setup can redact it. Errors are logged under
+// KeyError and redacted too, as they may quote prompt content, such as a
+// reason the user gave for dismissing a prompt.
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Redaction modes for prompt content.
+const (
+	RedactOff      = "off"      // Log prompts and answers in full
+	RedactHash     = "hash"     // Log a short SHA-256 hash, to correlate entries
+	RedactTruncate = "truncate" // Log the first few characters
+)
+
+// Attribute keys of prompt content.
+const (
+	KeyPrompt = "prompt"
+	KeyTitle  = "title"
+	KeyAnswer = "answer"
+	KeyError  = "error"
+)
+
+// truncateLength is how many characters RedactTruncate keeps.
+const truncateLength = 20
+
+// StderrFile is the Options.File value that logs to stderr.
+const StderrFile = "-"
+
+// Options configures Setup.
+type Options struct {
+	Level  string // debug, info, warn or error
+	Format string // FormatText or FormatJSON
+	File   string // Log file, or StderrFile
+	Redact string // RedactOff, RedactHash or RedactTruncate
+}
+
+// DefaultOptions returns the options of a binary logging to file.
+func DefaultOptions(file string) Options {
+	return Options{Level: "info", Format: FormatText, File: file, Redact: RedactOff}
+}
+
+// DefaultFile returns name.log in user-prompt-mcp in the user's cache
+// directory, or StderrFile if there is none.
+func DefaultFile(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return StderrFile
+	}
+	return filepath.Join(dir, "user-prompt-mcp", name+".log")
+}
+
+// RegisterFlags adds the --log-level, --log-format, --log-file and
+// --log-redact flags, with opts as their defaults.
+func RegisterFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.Level, "log-level", opts.Level, "Minimum log level: 'debug', 'info', 'warn' or 'error'")
+	fs.StringVar(&opts.Format, "log-format", opts.Format, "Log format: 'text' or 'json'")
+	fs.StringVar(&opts.File, "log-file", opts.File, "File the log is appended to, or '-' for stderr")
+	fs.StringVar(&opts.Redact, "log-redact", opts.Redact, "How prompts, answers and errors appear in the log: 'off' (in full), 'hash' (a short SHA-256 hash) or 'truncate' (the first 20 characters)")
+}
+
+// Setup installs the logger described by opts as the slog default, which
+// also receives the output of the standard log package. The returned closer
+// closes the log file.
+func Setup(opts Options) (io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, expected 'debug', 'info', 'warn' or 'error'", opts.Level)
+	}
+	switch opts.Redact {
+	case RedactOff, RedactHash, RedactTruncate:
+	default:
+		return nil, fmt.Errorf("invalid log redaction %q, expected %q, %q or %q", opts.Redact, RedactOff, RedactHash, RedactTruncate)
+	}
+
+	var w io.Writer = os.Stderr
+	var closer io.Closer = io.NopCloser(nil)
+	if opts.File != "" && opts.File != StderrFile {
+		if err := os.MkdirAll(filepath.Dir(opts.File), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w, closer = f, f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if opts.Redact != RedactOff {
+		handlerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case KeyPrompt, KeyTitle, KeyAnswer, KeyError:
+				switch v := a.Value.Any().(type) {
+				case string:
+					a.Value = slog.StringValue(redact(opts.Redact, v))
+				case error:
+					a.Value = slog.StringValue(redact(opts.Redact, v.Error()))
+				}
+			}
+			return a
+		}
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		closer.Close()
+		return nil, fmt.Errorf("invalid log format %q, expected %q or %q", opts.Format, FormatText, FormatJSON)
+	}
+	slog.SetDefault(slog.New(handler))
+	return closer, nil
+}
+
+func redact(mode, s string) string {
+	if s == "" {
+		return s
+	}
+	switch mode {
+	case RedactHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	case RedactTruncate:
+		runes := []rune(s)
+		if len(runes) <= truncateLength {
+			return s
+		}
+		return fmt.Sprintf("%s… (%d characters)", strings.TrimSpace(string(runes[:truncateLength])), len(runes))
+	}
+	return s
+}
+
+// Prompt returns the attribute of a prompt's text.
+func Prompt(text string) slog.Attr {
+	return slog.String(KeyPrompt, text)
+}
+
+// Title returns the attribute of a prompt's title.
+func Title(title string) slog.Attr {
+	return slog.String(KeyTitle, title)
+}
+
+// Answer returns the attribute of the user's answer.
+func Answer(answer string) slog.Attr {
+	return slog.String(KeyAnswer, answer)
+}
+
+// Fatal logs msg at the error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// logTo sets up logging to a file with opts and returns a function reading
+// back the JSON entries.
+func logTo(t *testing.T, opts Options) func() []map[string]any {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	opts.File = filepath.Join(t.TempDir(), "logs", "test.log")
+	opts.Format = FormatJSON
+	closer, err := Setup(opts)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { closer.Close() })
+
+	return func() []map[string]any {
+		data, err := os.ReadFile(opts.File)
+		if err != nil {
+			t.Fatalf("Failed to read log file: %v", err)
+		}
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Invalid JSON log line %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	longAnswer := "a rather long answer that goes on"
+	promptErr := errors.New("prompt dismissed by the user: secret reason")
+	tests := map[string]struct {
+		prompt, answer, error string
+	}{
+		RedactOff:      {"secret plan", longAnswer, promptErr.Error()},
+		RedactHash:     {"sha256:", "sha256:", "sha256:"},
+		RedactTruncate: {"secret plan", "a rather long answer… (33 characters)", "prompt dismissed by… (43 characters)"},
+	}
+	for mode, want := range tests {
+		t.Run(mode, func(t *testing.T) {
+			read := logTo(t, Options{Level: "info", Redact: mode})
+			slog.Info("Prompt answered", Prompt("secret plan"), Answer(longAnswer), "prompt_id", "p1")
+			slog.Warn("Prompt failed", KeyError, promptErr)
+
+			entries := read()
+			entry := entries[0]
+			if got := entry[KeyPrompt].(string); !strings.HasPrefix(got, want.prompt) {
+				t.Errorf("Expected prompt %q, got %q", want.prompt, got)
+			}
+			if got := entry[KeyAnswer].(string); !strings.HasPrefix(got, want.answer) {
+				t.Errorf("Expected answer %q, got %q", want.answer, got)
+			}
+			if entry["prompt_id"] != "p1" {
+				t.Errorf("Expected other attributes to be kept, got %v", entry["prompt_id"])
+			}
+			if got := entries[1][KeyError].(string); !strings.HasPrefix(got, want.error) {
+				t.Errorf("Expected error %q, got %q", want.error, got)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	read := logTo(t, Options{Level: "warn", Redact: RedactOff})
+	slog.Info("hidden")
+	slog.Warn("shown")
+
+	entries := read()
+	if len(entries) != 1 || entries[0]["msg"] != "shown" {
+		t.Errorf("Expected only the warning, got %v", entries)
+	}
+}
+
+func TestStandardLogIsRedirected(t *testing.T) {
+	read := logTo(t, Options{Level: "info", Redact: RedactOff})
+	log.Printf("from the log package")
+
+	entries := read()
+	if len(entries) != 1 || entries[0]["msg"] != "from the log package" {
+		t.Errorf("Expected the standard log output in the log file, got %v", entries)
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"level":  {Level: "loud", Format: FormatText, Redact: RedactOff},
+		"format": {Level: "info", Format: "xml", Redact: RedactOff},
+		"redact": {Level: "info", Format: FormatText, Redact: "blur"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts.File = StderrFile
+			if _, err := Setup(opts); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package protocol defines the HTTP API messages that user-prompt-mcp sends
+// to user-prompt-server, shared by the client and the server.
+package protocol
+
+import "time"
+
+// TriggerPromptRequest is the body of POST /api/trigger-prompt.
+type TriggerPromptRequest struct {
+	ID        string `json:"id,omitempty"` // Optional client-chosen prompt ID, so the client can poll its status
+	Prompt    string `json:"prompt"`
+	Title     string `json:"title"`
+	TimeoutMs int64  `json:"timeout_ms"`
+	// Optional overrides of the server's no-UI policy and grace period
+	NoUIPolicy  string `json:"no_ui_policy,omitempty"`
+	NoUIGraceMs int64  `json:"no_ui_grace_ms,omitempty"`
+	// Queued is the number of prompts waiting in the client behind this one
+	// when it was sent.
+	Queued int `json:"queued,omitempty"`
+}
+
+// TriggerPromptResponse is the response of POST /api/trigger-prompt, sent
+// once the prompt is answered or fails.
+type TriggerPromptResponse struct {
+	Input    string          `json:"input,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Code     string          `json:"code,omitempty"` // Machine-readable error reason, e.g. "no_ui_connected"
+	Presence *PromptPresence `json:"presence,omitempty"`
+}
+
+// Machine-readable TriggerPromptResponse.Code values.
+const (
+	ErrorCodeNoUIConnected  = "no_ui_connected" // Nobody could see the prompt
+	ErrorCodeDismissed      = "dismissed"       // The user dismissed the prompt in the UI
+	ErrorCodeShuttingDown   = "shutting_down"   // The server stopped before the prompt was answered
+	ErrorCodePromptConflict = "prompt_conflict" // Another prompt is already shown
+)
+
+// PromptPresence tells the waiting client whether the user has seen the
+// prompt. It is returned by /api/prompts/{id}/status and included in the
+// /api/trigger-prompt response.
+type PromptPresence struct {
+	UIClients int        `json:"ui_clients"`
+	Delivered bool       `json:"delivered"` // A UI acknowledged displaying the prompt
+	Viewed    bool       `json:"viewed"`
+	ViewedAt  *time.Time `json:"viewed_at,omitempty"`
+	Typing    bool       `json:"typing"`
+}
+
+// Describe returns a short human-readable summary of the presence.
+func (p PromptPresence) Describe() string {
+	switch {
+	case p.Typing:
+		return "user is typing"
+	case p.Viewed:
+		return "user has seen the prompt"
+	case p.UIClients == 0:
+		return "no UI connected"
+	case p.Delivered:
+		return "prompt is displayed, user has not seen it yet"
+	default:
+		return "user has not seen the prompt yet"
+	}
+}
+
+// PromptStatus is the response of /api/prompts/{id}/status.
+type PromptStatus struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+	PromptPresence
+}
@@ -0,0 +1,23 @@
+package protocol
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SocketPath returns the socket path of a Unix domain socket address such as
+// "unix:///run/user/1000/user-prompt.sock" or "unix:relative.sock", and false
+// for any other address.
+func SocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, "unix:") {
+		return "", false
+	}
+	u, err := url.Parse(addr)
+	if err != nil || u.Host != "" {
+		return strings.TrimPrefix(strings.TrimPrefix(addr, "unix:"), "//"), true
+	}
+	if u.Opaque != "" {
+		return u.Opaque, true
+	}
+	return u.Path, true
+}
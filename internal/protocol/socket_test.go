@@ -0,0 +1,22 @@
+package protocol
+
+import "testing"
+
+func TestSocketPath(t *testing.T) {
+	tests := []struct {
+		url  string
+		path string
+		ok   bool
+	}{
+		{"unix:///run/user/1000/user-prompt.sock", "/run/user/1000/user-prompt.sock", true},
+		{"unix:/tmp/user-prompt.sock", "/tmp/user-prompt.sock", true},
+		{"unix:user-prompt.sock", "user-prompt.sock", true},
+		{"http://localhost:3030", "", false},
+	}
+	for _, tt := range tests {
+		path, ok := SocketPath(tt.url)
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("SocketPath(%q) = %q, %t; expected %q, %t", tt.url, path, ok, tt.path, tt.ok)
+		}
+	}
+}
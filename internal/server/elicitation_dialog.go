@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/nazar256/user-prompt-mcp/pkg/gui"
+)
+
+// ElicitationProvider is the name ElicitationDialog reports with
+// gui.ReportProvider.
+const ElicitationProvider = "elicitation"
+
+// elicitationAnswerField is the field of the elicitation form that holds
+// the user's answer.
+const elicitationAnswerField = "answer"
+
+// Errors returned when the user does not answer an elicitation.
+var (
+	ErrElicitationDeclined  = errors.New("the user declined to answer")
+	ErrElicitationCancelled = errors.New("the user dismissed the prompt")
+)
+
+// ElicitationDialog implements gui.DialogProvider by asking the MCP client
+// to show the prompt itself (elicitation/create), over the session of the
+// tool call. If the client did not advertise the elicitation capability,
+// the prompt is shown with the fallback provider instead.
+type ElicitationDialog struct {
+	Fallback gui.DialogProvider
+}
+
+// NewElicitationDialog creates a new ElicitationDialog.
+func NewElicitationDialog(fallback gui.DialogProvider) *ElicitationDialog {
+	return &ElicitationDialog{Fallback: fallback}
+}
+
+// ShowInputDialog shows the prompt in the MCP client, or with the fallback
+// provider if the client cannot show it.
+func (ed *ElicitationDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	session, ok := elicitationSession(ctx)
+	if !ok {
+		slog.Debug("MCP client does not support elicitation, using the fallback provider", "component", "elicitation_dialog")
+		return ed.Fallback.ShowInputDialog(ctx, prompt, title)
+	}
+
+	gui.ReportProvider(ctx, ElicitationProvider)
+	gui.ReportStatus(ctx, "waiting for the answer in the MCP client")
+	request := mcp.ElicitationRequest{
+		Request: mcp.Request{Method: string(mcp.MethodElicitationCreate)},
+		Params: mcp.ElicitationParams{
+			Message: prompt,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					elicitationAnswerField: map[string]any{
+						"type":  "string",
+						"title": title,
+					},
+				},
+				"required": []string{elicitationAnswerField},
+			},
+		},
+	}
+	result, err := session.RequestElicitation(ctx, request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("elicitation request failed: %w", err)
+	}
+
+	switch result.Action {
+	case mcp.ElicitationResponseActionAccept:
+		content, _ := result.Content.(map[string]any)
+		answer, ok := content[elicitationAnswerField].(string)
+		if !ok {
+			return "", fmt.Errorf("elicitation response has no %q string", elicitationAnswerField)
+		}
+		return answer, nil
+	case mcp.ElicitationResponseActionDecline:
+		return "", ErrElicitationDeclined
+	default:
+		return "", ErrElicitationCancelled
+	}
+}
+
+// CheckDependencies checks the fallback provider, which is used until a
+// client supporting elicitation connects.
+func (ed *ElicitationDialog) CheckDependencies() error {
+	if err := ed.Fallback.CheckDependencies(); err != nil {
+		return fmt.Errorf("fallback provider: %w", err)
+	}
+	return nil
+}
+
+// elicitationSession returns the MCP session of the tool call in ctx if its
+// client advertised the elicitation capability.
+func elicitationSession(ctx context.Context) (server.SessionWithElicitation, bool) {
+	session := server.ClientSessionFromContext(ctx)
+	withInfo, ok := session.(server.SessionWithClientInfo)
+	if !ok || withInfo.GetClientCapabilities().Elicitation == nil {
+		return nil, false
+	}
+	withElicitation, ok := session.(server.SessionWithElicitation)
+	return withElicitation, ok
+}
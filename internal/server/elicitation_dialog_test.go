@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/nazar256/user-prompt-mcp/pkg/prompt"
+)
+
+// elicitingSession is a testSession whose client supports elicitation and
+// responds with a fixed result.
+type elicitingSession struct {
+	testSession
+	capabilities mcp.ClientCapabilities
+	result       mcp.ElicitationResult
+	requests     []mcp.ElicitationRequest
+}
+
+func (s *elicitingSession) GetClientInfo() mcp.Implementation              { return mcp.Implementation{} }
+func (s *elicitingSession) SetClientInfo(mcp.Implementation)               {}
+func (s *elicitingSession) GetClientCapabilities() mcp.ClientCapabilities  { return s.capabilities }
+func (s *elicitingSession) SetClientCapabilities(c mcp.ClientCapabilities) { s.capabilities = c }
+
+func (s *elicitingSession) RequestElicitation(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	s.requests = append(s.requests, request)
+	return &s.result, nil
+}
+
+// fixedDialog is a DialogProvider that always answers, or fails, the same.
+type fixedDialog struct {
+	answer string
+	err    error
+}
+
+func (d *fixedDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	return d.answer, d.err
+}
+
+func (d *fixedDialog) CheckDependencies() error {
+	return nil
+}
+
+func TestElicitationDialog(t *testing.T) {
+	accept := mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{
+		Action:  mcp.ElicitationResponseActionAccept,
+		Content: map[string]any{"answer": "from the client"},
+	}}
+	tests := []struct {
+		name         string
+		capabilities mcp.ClientCapabilities
+		result       mcp.ElicitationResult
+		expected     string
+		err          error
+		elicited     bool
+	}{
+		{"accepted", mcp.ClientCapabilities{Elicitation: &mcp.ElicitationCapability{}}, accept, "from the client", nil, true},
+		{"declined", mcp.ClientCapabilities{Elicitation: &mcp.ElicitationCapability{}},
+			mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}}, "", ErrElicitationDeclined, true},
+		{"not supported", mcp.ClientCapabilities{}, accept, "from the fallback", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := prompt.NewService(prompt.ServiceOptions{Dialog: NewElicitationDialog(&fixedDialog{answer: "from the fallback"})})
+			mcpServer := NewMCPServer(service)
+			session := &elicitingSession{capabilities: tt.capabilities, result: tt.result}
+			ctx := mcpServer.mcpServer.WithContext(context.Background(), session)
+
+			answer, err := service.PromptForInput(ctx, prompt.PromptOptions{Prompt: "Continue?", Title: "Deploy"})
+			if answer != tt.expected || !errors.Is(err, tt.err) {
+				t.Errorf("Expected %q, %v; got %q, %v", tt.expected, tt.err, answer, err)
+			}
+			if elicited := len(session.requests) > 0; elicited != tt.elicited {
+				t.Fatalf("Expected elicitation %t, got %t", tt.elicited, elicited)
+			}
+			if tt.elicited && session.requests[0].Params.Message != "Continue?" {
+				t.Errorf("Expected the prompt as the elicitation message, got %q", session.requests[0].Params.Message)
+			}
+		})
+	}
+}
@@ -4,11 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/nazar256/user-prompt-mcp/internal/logging"
+	"github.com/nazar256/user-prompt-mcp/pkg/gui"
 	"github.com/nazar256/user-prompt-mcp/pkg/prompt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -20,10 +29,15 @@ const (
 	UserPromptToolName = "user_prompt"
 )
 
+// tracerName names the tracer, which is looked up from the global provider
+// at span start so that a provider installed later is used.
+const tracerName = "github.com/nazar256/user-prompt-mcp/internal/server"
+
 // MCPServer represents the MCP server for user input
 type MCPServer struct {
-	promptService *prompt.Service
-	mcpServer     *server.MCPServer
+	promptService    *prompt.Service
+	mcpServer        *server.MCPServer
+	progressInterval time.Duration
 }
 
 // NewMCPServer creates a new MCP Server for user input
@@ -31,16 +45,16 @@ func NewMCPServer(promptService *prompt.Service) *MCPServer {
 	// Create the MCP server with hooks for debugging
 	hooks := &server.Hooks{}
 
-	hooks.AddBeforeAny(func(id any, method mcp.MCPMethod, message any) {
-		log.Printf("Request: [%v] %s", id, method)
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		slog.Debug("MCP request", "id", id, "method", method)
 	})
 
-	hooks.AddOnSuccess(func(id any, method mcp.MCPMethod, message any, result any) {
-		log.Printf("Success: [%v] %s", id, method)
+	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
+		slog.Debug("MCP request succeeded", "id", id, "method", method)
 	})
 
-	hooks.AddOnError(func(id any, method mcp.MCPMethod, message any, err error) {
-		log.Printf("Error: [%v] %s: %v", id, method, err)
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		slog.Warn("MCP request failed", "id", id, "method", method, "error", err)
 	})
 
 	// Create the server with error logging enabled
@@ -52,11 +66,18 @@ func NewMCPServer(promptService *prompt.Service) *MCPServer {
 	)
 
 	return &MCPServer{
-		promptService: promptService,
-		mcpServer:     mcpServer,
+		promptService:    promptService,
+		mcpServer:        mcpServer,
+		progressInterval: DefaultProgressInterval,
 	}
 }
 
+// SetProgressInterval sets how often progress notifications are sent while
+// a user_prompt call waits for the user, if the client asked for them.
+func (s *MCPServer) SetProgressInterval(interval time.Duration) {
+	s.progressInterval = interval
+}
+
 // RegisterUserPromptTool registers the user prompt tool with the MCP server
 func (s *MCPServer) RegisterUserPromptTool() {
 	// Create the user_prompt tool definition
@@ -70,46 +91,105 @@ func (s *MCPServer) RegisterUserPromptTool() {
 		mcp.WithString("title",
 			mcp.Description("The title of the dialog window (optional)"),
 		),
+		mcp.WithNumber("priority",
+			mcp.Description("Priority among prompts waiting to be shown; higher is shown first (optional, default 0)"),
+		),
+		mcp.WithOutputSchema[PromptResult](),
 	)
 
 	// Register the tool handler
 	s.mcpServer.AddTool(tool, s.userPromptHandler)
-	log.Printf("Registered tool: %s", UserPromptToolName)
+	slog.Debug("Registered tool", "tool", UserPromptToolName)
 }
 
 // userPromptHandler handles calls to the user_prompt tool
 func (s *MCPServer) userPromptHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start := time.Now()
 	// Extract the prompt and title from the request
-	promptText, ok := request.Params.Arguments["prompt"].(string)
+	args := request.GetArguments()
+	promptText, ok := args["prompt"].(string)
 	if !ok {
 		return nil, errors.New("prompt argument must be a string")
 	}
 
 	// Title is optional
 	var title string
-	if titleArg, exists := request.Params.Arguments["title"]; exists {
+	if titleArg, exists := args["title"]; exists {
 		if titleStr, ok := titleArg.(string); ok {
 			title = titleStr
 		}
 	}
 
-	log.Printf("User prompt request: prompt=%q, title=%q", promptText, title)
+	// Priority is optional; JSON numbers arrive as float64
+	var priority int
+	if priorityArg, ok := args["priority"].(float64); ok {
+		priority = int(priorityArg)
+	}
+
+	slog.Info("User prompt requested", logging.Prompt(promptText), logging.Title(title), "priority", priority)
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+UserPromptToolName,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("mcp.tool.name", UserPromptToolName), attribute.Int("prompt.priority", priority)))
+	defer span.End()
+
+	// The provider reports itself from the dialog goroutine, which may
+	// outlive the call after a timeout.
+	var provider atomic.Value
+	provider.Store("")
+	ctx = gui.WithProviderReporter(ctx, func(name string) { provider.Store(name) })
+
+	promptOpts := prompt.PromptOptions{
+		ID:       uuid.NewString(),
+		Prompt:   promptText,
+		Title:    title,
+		Priority: priority,
+	}
+
+	// Keep the client informed while waiting, if it passed a progress token
+	if request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+		progress := newProgressNotifier(ctx, request.Params.Meta.ProgressToken, s.promptService.Timeout(), s.mcpServer.SendNotificationToClient)
+		promptOpts.OnQueueUpdate = progress.queueUpdate
+		ctx = gui.WithStatusReporter(ctx, progress.statusUpdate)
+
+		progressCtx, stopProgress := context.WithCancel(ctx)
+		progressDone := make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			progress.run(progressCtx, s.progressInterval)
+		}()
+		// No notifications may be sent once the tool call has returned
+		defer func() {
+			stopProgress()
+			<-progressDone
+		}()
+	}
 
 	// Display the prompt to the user and get their input
-	userInput, err := s.promptService.PromptForInput(ctx, prompt.PromptOptions{
-		Prompt: promptText,
-		Title:  title,
-	})
+	userInput, err := s.promptService.PromptForInput(ctx, promptOpts)
+
+	var shownAt *time.Time
+	if record, ok := s.promptService.Lookup(promptOpts.ID); ok {
+		shownAt = record.ShownAt
+	}
+	result := newPromptResult(promptOpts.ID, start, shownAt, provider.Load().(string), userInput, err)
+	span.SetAttributes(attribute.String("prompt.outcome", result.Outcome), attribute.String("prompt.provider", result.Provider))
 
+	// Failures are tool results rather than protocol errors, so that the
+	// model sees them and their outcome
 	if err != nil {
-		log.Printf("Error getting user input: %v", err)
-		return nil, fmt.Errorf("failed to get user input: %w", err)
+		slog.Warn("Failed to get user input", "prompt_id", result.PromptID, "outcome", result.Outcome, "error", err)
+		span.SetStatus(codes.Error, err.Error())
+		toolResult := mcp.NewToolResultStructured(result, fmt.Sprintf("failed to get user input: %v", err))
+		toolResult.IsError = true
+		return toolResult, nil
 	}
 
-	log.Printf("User provided input: %q", userInput)
+	slog.Info("User answered", "prompt_id", result.PromptID, "provider", result.Provider, "latency_ms", result.LatencyMs, logging.Answer(userInput))
 
-	// Return the user's input
-	return mcp.NewToolResultText(userInput), nil
+	// The text content is the user's input, as for clients that ignore
+	// structured content
+	return mcp.NewToolResultStructured(result, userInput), nil
 }
 
 // GetMCPServer returns the underlying MCP server
@@ -119,6 +199,8 @@ func (s *MCPServer) GetMCPServer() *server.MCPServer {
 
 // ServeStdio runs the server using the stdio transport
 func (s *MCPServer) ServeStdio() error {
-	log.Println("Starting MCP server using stdio transport")
-	return server.ServeStdio(s.mcpServer)
+	slog.Info("Starting MCP server using stdio transport")
+	// Route the transport's errors to the configured log rather than stderr
+	errorLogger := slog.NewLogLogger(slog.Default().Handler(), slog.LevelError)
+	return server.ServeStdio(s.mcpServer, server.WithErrorLogger(errorLogger))
 }
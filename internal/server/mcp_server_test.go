@@ -2,8 +2,14 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/nazar256/user-prompt-mcp/pkg/gui"
 	"github.com/nazar256/user-prompt-mcp/pkg/prompt"
 )
 
@@ -42,5 +48,282 @@ func TestMCPServer_RegisterUserPromptTool(t *testing.T) {
 	// Register the user_prompt tool
 	mcpServer.RegisterUserPromptTool()
 
-	// We're just testing that the method runs without error
+	// The tool declares the schema of its structured content
+	tool := mcpServer.mcpServer.GetTool(UserPromptToolName)
+	if tool == nil {
+		t.Fatal("Tool not registered")
+	}
+	if tool.Tool.OutputSchema.Type != "object" {
+		t.Fatalf("Expected an object output schema, got %+v", tool.Tool.OutputSchema)
+	}
+	for _, property := range []string{"prompt_id", "outcome", "answer", "provider", "latency_ms", "response_ms", "error"} {
+		if _, ok := tool.Tool.OutputSchema.Properties[property]; !ok {
+			t.Errorf("Expected output schema property %q", property)
+		}
+	}
+}
+
+// idDialog answers with the prompt ID it was given.
+type idDialog struct{}
+
+func (d *idDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	return gui.PromptID(ctx), nil
+}
+
+func (d *idDialog) CheckDependencies() error {
+	return nil
+}
+
+func TestUserPromptHandler_PassesPromptIDToDialog(t *testing.T) {
+	mcpServer := NewMCPServer(prompt.NewService(prompt.ServiceOptions{Dialog: &idDialog{}}))
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{"prompt": "Deploy?"}
+	toolResult, err := mcpServer.userPromptHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	result := toolResult.StructuredContent.(PromptResult)
+	if result.PromptID == "" || result.Answer != result.PromptID {
+		t.Errorf("Expected the dialog to get prompt ID %q, got %q", result.PromptID, result.Answer)
+	}
+}
+
+// statusDialog reports a status update, then answers after a delay.
+type statusDialog struct {
+	delay time.Duration
+}
+
+func (d *statusDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	gui.ReportStatus(ctx, "user is typing")
+	time.Sleep(d.delay)
+	return "answer", nil
+}
+
+func (d *statusDialog) CheckDependencies() error {
+	return nil
+}
+
+// testSession is a ClientSession that collects notifications.
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return "test" }
+
+func TestUserPromptHandler_ProgressNotifications(t *testing.T) {
+	service := prompt.NewService(prompt.ServiceOptions{Dialog: &statusDialog{delay: 50 * time.Millisecond}})
+	mcpServer := NewMCPServer(service)
+	mcpServer.SetProgressInterval(10 * time.Millisecond)
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+	ctx := mcpServer.mcpServer.WithContext(context.Background(), session)
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]interface{}{"prompt": "Continue?"}
+	request.Params.Meta = &mcp.Meta{ProgressToken: "token-1"}
+
+	if _, err := mcpServer.userPromptHandler(ctx, request); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	close(session.notifications)
+
+	var count int
+	var sawTyping bool
+	lastProgress := -1.0
+	for notification := range session.notifications {
+		if notification.Method != "notifications/progress" {
+			t.Errorf("Unexpected notification %q", notification.Method)
+			continue
+		}
+		params := notification.Params.AdditionalFields
+		if params["progressToken"] != "token-1" {
+			t.Errorf("Expected progress token 'token-1', got %v", params["progressToken"])
+		}
+		progress, _ := params["progress"].(float64)
+		if progress < lastProgress {
+			t.Errorf("Expected progress to increase, got %v after %v", progress, lastProgress)
+		}
+		lastProgress = progress
+		if message, _ := params["message"].(string); strings.Contains(message, "user is typing") {
+			sawTyping = true
+		}
+		count++
+	}
+	if count < 2 {
+		t.Errorf("Expected several progress notifications, got %d", count)
+	}
+	if !sawTyping {
+		t.Error("Expected a progress message with the provider status")
+	}
+}
+
+func TestProgressNotifier_Queued(t *testing.T) {
+	progress := newProgressNotifier(context.Background(), 1, time.Minute, nil)
+	progress.queueUpdate(2)
+
+	params := progress.params(progress.start.Add(5 * time.Second))
+	if message := params["message"].(string); !strings.Contains(message, "Queued behind 2 prompt(s)") {
+		t.Errorf("Expected queued message, got %q", message)
+	}
+	if _, ok := params["total"]; ok {
+		t.Error("Expected no total while queued")
+	}
+
+	progress.queueUpdate(0)
+	params = progress.params(progress.shownAt.Add(10 * time.Second))
+	if message := params["message"].(string); !strings.Contains(message, "50s remaining") {
+		t.Errorf("Expected remaining time once shown, got %q", message)
+	}
+}
+
+// readResource sends resources/read for uri through the MCP message handler.
+func readResource(t *testing.T, s *MCPServer, uri string) mcp.JSONRPCMessage {
+	t.Helper()
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": uri},
+	})
+	return s.mcpServer.HandleMessage(context.Background(), message)
+}
+
+func TestPromptResources(t *testing.T) {
+	service := prompt.NewService(prompt.ServiceOptions{Dialog: &statusDialog{}})
+	mcpServer := NewMCPServer(service)
+	mcpServer.RegisterPromptResources()
+
+	if _, err := service.PromptForInput(context.Background(), prompt.PromptOptions{ID: "p1", Prompt: "Continue?"}); err != nil {
+		t.Fatalf("PromptForInput failed: %v", err)
+	}
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{PendingPromptsURI, "[]"},
+		{PromptHistoryURI, `"answer": "answer"`},
+		{"prompts://history/p1", `"id": "p1"`},
+	}
+	for _, tt := range tests {
+		response, ok := readResource(t, mcpServer, tt.uri).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Errorf("Expected a result for %s", tt.uri)
+			continue
+		}
+		contents := response.Result.(mcp.ReadResourceResult).Contents
+		text := contents[0].(mcp.TextResourceContents).Text
+		if !strings.Contains(text, tt.expected) {
+			t.Errorf("Expected %s to contain %s, got %s", tt.uri, tt.expected, text)
+		}
+	}
+
+	if _, ok := readResource(t, mcpServer, "prompts://history/unknown").(mcp.JSONRPCError); !ok {
+		t.Error("Expected an error for an unknown prompt ID")
+	}
+}
+
+func TestPromptTemplates(t *testing.T) {
+	mcpServer := NewMCPServer(&prompt.Service{})
+	mcpServer.RegisterPromptTemplates()
+
+	tests := []struct {
+		name      string
+		arguments map[string]string
+		expected  string
+	}{
+		{AlwaysAskPromptName, nil, "do not finish responding"},
+		{AskBeforeDestructivePromptName, nil, "force-pushing"},
+		{AskBeforeDestructivePromptName, map[string]string{"actions": "dropping tables"}, "Before dropping tables,"},
+		{CheckpointReviewPromptName, map[string]string{"checkpoint": "after every commit"}, "and, after every commit, call"},
+	}
+	for _, tt := range tests {
+		message, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  "prompts/get",
+			"params":  map[string]any{"name": tt.name, "arguments": tt.arguments},
+		})
+		response, ok := mcpServer.mcpServer.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Errorf("Expected a result for %s", tt.name)
+			continue
+		}
+		result := response.Result.(mcp.GetPromptResult)
+		text := result.Messages[0].Content.(mcp.TextContent).Text
+		if !strings.Contains(text, tt.expected) || !strings.Contains(text, "`"+UserPromptToolName+"`") {
+			t.Errorf("Expected %s to mention %q and the tool, got %q", tt.name, tt.expected, text)
+		}
+	}
+}
+
+func TestUserPromptHandler_StructuredResult(t *testing.T) {
+	accept := mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{
+		Action:  mcp.ElicitationResponseActionAccept,
+		Content: map[string]any{"answer": "ship it"},
+	}}
+	tests := []struct {
+		name     string
+		dialog   gui.DialogProvider
+		timeout  time.Duration
+		expected PromptResult
+		text     string
+	}{
+		{
+			name:     "answered",
+			dialog:   NewElicitationDialog(&fixedDialog{}),
+			expected: PromptResult{Outcome: OutcomeAnswered, Answer: "ship it", AnswerKind: AnswerKindFreeText, Provider: ElicitationProvider},
+			text:     "ship it",
+		},
+		{
+			name:     "timed out",
+			dialog:   &statusDialog{delay: time.Second},
+			timeout:  20 * time.Millisecond,
+			expected: PromptResult{Outcome: OutcomeTimedOut, Error: "prompt timed out after 20ms"},
+			text:     "failed to get user input: prompt timed out after 20ms",
+		},
+		{
+			name:     "dismissed in the UI",
+			dialog:   &fixedDialog{err: fmt.Errorf("%w: not now", gui.ErrPromptDismissed)},
+			expected: PromptResult{Outcome: OutcomeDismissed, Error: "prompt dismissed by the user: not now"},
+			text:     "failed to get user input: prompt dismissed by the user: not now",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := prompt.NewService(prompt.ServiceOptions{Dialog: tt.dialog, Timeout: tt.timeout})
+			mcpServer := NewMCPServer(service)
+			session := &elicitingSession{capabilities: mcp.ClientCapabilities{Elicitation: &mcp.ElicitationCapability{}}, result: accept}
+			ctx := mcpServer.mcpServer.WithContext(context.Background(), session)
+
+			var request mcp.CallToolRequest
+			request.Params.Arguments = map[string]any{"prompt": "Deploy?"}
+			toolResult, err := mcpServer.userPromptHandler(ctx, request)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if text := toolResult.Content[0].(mcp.TextContent).Text; text != tt.text {
+				t.Errorf("Expected text content %q, got %q", tt.text, text)
+			}
+			if toolResult.IsError != (tt.expected.Error != "") {
+				t.Errorf("Expected IsError %t, got %t", tt.expected.Error != "", toolResult.IsError)
+			}
+
+			result := toolResult.StructuredContent.(PromptResult)
+			if _, ok := service.Lookup(result.PromptID); !ok {
+				t.Errorf("Expected prompt ID %q to be in the history", result.PromptID)
+			}
+			if result.Outcome != tt.expected.Outcome || result.Answer != tt.expected.Answer || result.AnswerKind != tt.expected.AnswerKind ||
+				result.Provider != tt.expected.Provider || result.Error != tt.expected.Error {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+			if result.ResponseMs == nil || result.LatencyMs < *result.ResponseMs {
+				t.Errorf("Expected the latency to include the response time, got %d and %v", result.LatencyMs, result.ResponseMs)
+			}
+		})
+	}
 }
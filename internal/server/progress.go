@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultProgressInterval is how often progress notifications are sent while
+// waiting for the user.
+const DefaultProgressInterval = 10 * time.Second
+
+// progressNotifier sends notifications/progress for a user_prompt call so
+// that MCP clients can tell the tool is waiting for a human, not hung.
+type progressNotifier struct {
+	ctx     context.Context
+	token   mcp.ProgressToken
+	send    func(ctx context.Context, method string, params map[string]any) error
+	timeout time.Duration
+	start   time.Time
+
+	mu      sync.Mutex
+	ahead   int
+	shownAt time.Time
+	status  string
+	changed chan struct{}
+}
+
+func newProgressNotifier(ctx context.Context, token mcp.ProgressToken, timeout time.Duration, send func(ctx context.Context, method string, params map[string]any) error) *progressNotifier {
+	now := time.Now()
+	return &progressNotifier{
+		ctx:     ctx,
+		token:   token,
+		send:    send,
+		timeout: timeout,
+		start:   now,
+		shownAt: now, // Until the queue reports otherwise
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// queueUpdate is a prompt.PromptOptions.OnQueueUpdate callback.
+func (p *progressNotifier) queueUpdate(ahead int) {
+	p.mu.Lock()
+	if ahead == 0 && p.ahead > 0 {
+		p.shownAt = time.Now()
+	}
+	p.ahead = ahead
+	p.mu.Unlock()
+	p.notifyChanged()
+}
+
+// statusUpdate is a gui.StatusFunc callback.
+func (p *progressNotifier) statusUpdate(status string) {
+	p.mu.Lock()
+	p.status = status
+	p.mu.Unlock()
+	p.notifyChanged()
+}
+
+func (p *progressNotifier) notifyChanged() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run sends a notification immediately, on every interval tick and on every
+// state change, until ctx is done.
+func (p *progressNotifier) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.notify()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.changed:
+		}
+	}
+}
+
+func (p *progressNotifier) notify() {
+	params := p.params(time.Now())
+	if err := p.send(p.ctx, "notifications/progress", params); err != nil {
+		slog.Warn("Failed to send progress notification", "error", err)
+	}
+}
+
+// params builds the notification. Progress is the number of seconds spent
+// waiting, which always increases; total is only known once the prompt is
+// shown and its timeout is running.
+func (p *progressNotifier) params(now time.Time) map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	params := map[string]any{
+		"progressToken": p.token,
+		"progress":      now.Sub(p.start).Seconds(),
+	}
+	elapsed := now.Sub(p.start).Round(time.Second)
+	if p.ahead > 0 {
+		params["message"] = fmt.Sprintf("Queued behind %d prompt(s), waited %v", p.ahead, elapsed)
+		return params
+	}
+
+	deadline := p.shownAt.Add(p.timeout)
+	params["total"] = deadline.Sub(p.start).Seconds()
+	remaining := max(deadline.Sub(now), 0).Round(time.Second)
+	message := fmt.Sprintf("Waiting for the user: %v elapsed, %v remaining", elapsed, remaining)
+	if p.status != "" {
+		message += " (" + p.status + ")"
+	}
+	params["message"] = message
+	return params
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Names of the MCP prompts.
+const (
+	AskBeforeDestructivePromptName = "ask-before-destructive-action"
+	CheckpointReviewPromptName     = "checkpoint-review"
+	AlwaysAskPromptName            = "always-ask-before-finishing"
+)
+
+// promptArgument is an optional argument of a promptTemplate, used in place
+// of its default when given.
+type promptArgument struct {
+	name        string
+	description string
+	fallback    string
+}
+
+// promptTemplate is an MCP prompt that instructs the model how to use the
+// user_prompt tool for a human-in-the-loop pattern.
+type promptTemplate struct {
+	name        string
+	description string
+	arguments   []promptArgument
+	// text is a format string; %[1]s is the tool name and the arguments
+	// follow in order.
+	text string
+}
+
+var promptTemplates = []promptTemplate{
+	{
+		name:        AskBeforeDestructivePromptName,
+		description: "Ask the user for confirmation with user_prompt before any destructive or irreversible action",
+		arguments: []promptArgument{{
+			name:        "actions",
+			description: "What counts as destructive (optional)",
+			fallback:    "deleting files or data, overwriting uncommitted changes, force-pushing, rewriting history, running database migrations, deploying or anything else that is hard to undo",
+		}},
+		text: "Before %[2]s, stop and ask the user for confirmation with the MCP tool `%[1]s`. " +
+			"Describe exactly what you are about to do and what will be affected, and wait for the answer. " +
+			"Only proceed if the user clearly approves; otherwise follow their instructions instead. " +
+			"Never assume approval because a similar action was approved earlier.",
+	},
+	{
+		name:        CheckpointReviewPromptName,
+		description: "Pause for a review with user_prompt at checkpoints during a longer task",
+		arguments: []promptArgument{{
+			name:        "checkpoint",
+			description: "When to pause for a review (optional)",
+			fallback:    "after finishing each step of your plan",
+		}},
+		text: "Work in steps and, %[2]s, call the MCP tool `%[1]s` to review the progress with the user. " +
+			"Summarize what you changed, what is left and any decisions or open questions, then wait for the answer. " +
+			"Adjust the rest of the plan to the user's feedback before you continue.",
+	},
+	{
+		name:        AlwaysAskPromptName,
+		description: "Keep the conversation going through user_prompt instead of finishing the response",
+		text: "Important: do not finish responding until the user explicitly tells you to. " +
+			"Instead, always use the MCP tool `%[1]s` and wait for the response in all cases: " +
+			"when you feel stuck, when you have a question and when you have finished working on a prompt. " +
+			"Always communicate with the user through this tool.",
+	},
+}
+
+// RegisterPromptTemplates registers MCP prompts with instructions for
+// common ways of keeping the user in the loop with the user_prompt tool.
+func (s *MCPServer) RegisterPromptTemplates() {
+	for _, tpl := range promptTemplates {
+		opts := []mcp.PromptOption{mcp.WithPromptDescription(tpl.description)}
+		for _, arg := range tpl.arguments {
+			opts = append(opts, mcp.WithArgument(arg.name, mcp.ArgumentDescription(arg.description)))
+		}
+		s.mcpServer.AddPrompt(mcp.NewPrompt(tpl.name, opts...), tpl.handle)
+	}
+	slog.Debug("Registered prompts", "count", len(promptTemplates))
+}
+
+func (tpl promptTemplate) handle(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	values := []any{UserPromptToolName}
+	for _, arg := range tpl.arguments {
+		value := strings.TrimSpace(request.Params.Arguments[arg.name])
+		if value == "" {
+			value = arg.fallback
+		}
+		values = append(values, value)
+	}
+	return mcp.NewGetPromptResult(tpl.description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(fmt.Sprintf(tpl.text, values...))),
+	}), nil
+}
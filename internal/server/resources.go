@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// URIs of the prompt resources.
+const (
+	PendingPromptsURI       = "prompts://pending"
+	PromptHistoryURI        = "prompts://history"
+	PromptRecordURITemplate = "prompts://history/{id}"
+)
+
+const jsonMIMEType = "application/json"
+
+// RegisterPromptResources registers resources that let MCP clients re-read
+// pending prompts and previous answers instead of asking the user again.
+func (s *MCPServer) RegisterPromptResources() {
+	s.mcpServer.AddResource(
+		mcp.NewResource(PendingPromptsURI, "Pending prompts",
+			mcp.WithResourceDescription("Prompts waiting to be shown or waiting for the user's answer"),
+			mcp.WithMIMEType(jsonMIMEType),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return jsonResource(request.Params.URI, s.promptService.Pending())
+		},
+	)
+	s.mcpServer.AddResource(
+		mcp.NewResource(PromptHistoryURI, "Prompt history",
+			mcp.WithResourceDescription("Recently finished prompts with the user's answers, most recent first"),
+			mcp.WithMIMEType(jsonMIMEType),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return jsonResource(request.Params.URI, s.promptService.History())
+		},
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(PromptRecordURITemplate, "Prompt",
+			mcp.WithTemplateDescription("A pending or finished prompt by ID, with the user's answer once given"),
+			mcp.WithTemplateMIMEType(jsonMIMEType),
+		),
+		s.promptRecordHandler,
+	)
+	slog.Debug("Registered resources", "uris", []string{PendingPromptsURI, PromptHistoryURI, PromptRecordURITemplate})
+}
+
+// promptRecordHandler reads prompts://history/{id}.
+func (s *MCPServer) promptRecordHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := templateArg(request.Params.Arguments["id"])
+	record, ok := s.promptService.Lookup(id)
+	if !ok {
+		return nil, fmt.Errorf("prompt %q not found; it may have dropped out of the history", id)
+	}
+	return jsonResource(request.Params.URI, record)
+}
+
+// templateArg returns a URI template variable, which mcp-go passes as the
+// list of values it matched.
+func templateArg(arg any) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func jsonResource(uri string, v any) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: jsonMIMEType, Text: string(data)}}, nil
+}
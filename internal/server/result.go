@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/pkg/gui"
+	"github.com/nazar256/user-prompt-mcp/pkg/prompt"
+)
+
+// Outcomes of a user_prompt call.
+const (
+	OutcomeAnswered  = "answered"
+	OutcomeTimedOut  = "timed_out"
+	OutcomeCancelled = "cancelled"
+	OutcomeDismissed = "dismissed" // The user declined or closed the prompt
+	OutcomeQueueFull = "queue_full"
+	OutcomeNoUI      = "no_ui_connected"
+	OutcomeError     = "error"
+)
+
+// AnswerKindFreeText is the kind of an answer the user typed. The tool does
+// not offer suggestions or default answers, so it is currently the only kind.
+const AnswerKindFreeText = "free_text"
+
+// PromptResult is the structured content of a user_prompt result. The text
+// content holds the answer, or the error, as before.
+type PromptResult struct {
+	// PromptID identifies the prompt in the prompts://history/{id} resource.
+	PromptID   string `json:"prompt_id"`
+	Outcome    string `json:"outcome"`
+	Answer     string `json:"answer,omitempty"`
+	AnswerKind string `json:"answer_kind,omitempty"`
+	// Provider is the dialog provider that showed the prompt, e.g. "remote".
+	Provider string `json:"provider,omitempty"`
+	// LatencyMs is the time from the tool call to its result, including any
+	// time the prompt was queued.
+	LatencyMs int64 `json:"latency_ms"`
+	// ResponseMs is the time from showing the prompt to the result.
+	ResponseMs *int64 `json:"response_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// newPromptResult describes the outcome of a prompt requested at start and
+// shown at shownAt, nil if it never was.
+func newPromptResult(promptID string, start time.Time, shownAt *time.Time, provider, answer string, err error) PromptResult {
+	now := time.Now()
+	result := PromptResult{
+		PromptID:  promptID,
+		Outcome:   outcomeOf(err),
+		Provider:  provider,
+		LatencyMs: now.Sub(start).Milliseconds(),
+	}
+	if shownAt != nil {
+		responseMs := now.Sub(*shownAt).Milliseconds()
+		result.ResponseMs = &responseMs
+	}
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Answer, result.AnswerKind = answer, AnswerKindFreeText
+	}
+	return result
+}
+
+func outcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeAnswered
+	case errors.Is(err, prompt.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimedOut
+	case errors.Is(err, context.Canceled):
+		return OutcomeCancelled
+	case errors.Is(err, prompt.ErrQueueFull):
+		return OutcomeQueueFull
+	case errors.Is(err, gui.ErrNoUIConnected):
+		return OutcomeNoUI
+	case errors.Is(err, gui.ErrPromptDismissed), errors.Is(err, ErrElicitationDeclined), errors.Is(err, ErrElicitationCancelled):
+		return OutcomeDismissed
+	default:
+		return OutcomeError
+	}
+}
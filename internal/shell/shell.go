@@ -0,0 +1,14 @@
+// Package shell runs user-configured command lines through the system
+// shell, for the hooks and providers that accept a shell command.
+package shell
+
+import "runtime"
+
+// Command returns the platform shell invocation for a command line: the
+// shell to execute and its arguments.
+func Command(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
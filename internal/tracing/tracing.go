@@ -0,0 +1,80 @@
+// Package tracing sets up OpenTelemetry tracing for user-prompt-mcp and
+// user-prompt-server.
+//
+// The packages of this module create spans through the global tracer
+// provider, which does nothing until Setup installs an OTLP exporter. Trace
+// context travels between client and server in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Standard OpenTelemetry environment variables that enable the exporter
+// without --otlp-endpoint.
+var otlpEndpointEnv = []string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"}
+
+// Options configures Setup.
+type Options struct {
+	// Endpoint is the OTLP/HTTP endpoint URL, e.g.
+	// "http://localhost:4318/v1/traces". Empty disables tracing, unless one
+	// of the standard OTEL_EXPORTER_OTLP_* endpoint variables is set.
+	Endpoint string
+	// ServiceName is the service.name of the spans.
+	ServiceName string
+}
+
+// RegisterFlags adds the --otlp-endpoint flag.
+func RegisterFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.Endpoint, "otlp-endpoint", opts.Endpoint, "OTLP/HTTP endpoint that receives traces, e.g. http://localhost:4318/v1/traces (default: $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or $OTEL_EXPORTER_OTLP_ENDPOINT; tracing is off if none is set)")
+}
+
+// Setup installs a tracer provider exporting to the OTLP endpoint and the
+// W3C trace context propagator. It returns a function that flushes and stops
+// the exporter, and is a no-op if tracing is not enabled.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+	if !enabled(opts) {
+		return noop, nil
+	}
+
+	var exporterOpts []otlptracehttp.Option
+	if opts.Endpoint != "" {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return noop, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return noop, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+func enabled(opts Options) bool {
+	if opts.Endpoint != "" {
+		return true
+	}
+	for _, name := range otlpEndpointEnv {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/shell"
+)
+
+// execWaitDelay is how long ShowInputDialog waits for the command's output
+// pipes to close after the process group has been killed.
+const execWaitDelay = 2 * time.Second
+
+// ExecDialog implements DialogProvider by running a user-configured command,
+// such as rofi, dmenu, zenity or a custom script, and reading the answer from
+// its stdout.
+//
+// The command is run by the system shell. The prompt is passed both in the
+// USER_PROMPT_TITLE and USER_PROMPT_TEXT environment variables and as a JSON
+// object ({"prompt": "...", "title": "..."}) on stdin, so it never has to be
+// quoted into the command line. A non-zero exit status is reported as an
+// error. When the context is cancelled the whole process group is killed.
+type ExecDialog struct {
+	Command string
+}
+
+// NewExecDialog creates a new ExecDialog running the given shell command.
+func NewExecDialog(command string) *ExecDialog {
+	return &ExecDialog{Command: command}
+}
+
+type execDialogInput struct {
+	Prompt string `json:"prompt"`
+	Title  string `json:"title"`
+}
+
+// ShowInputDialog runs the command and returns its stdout with the trailing
+// newline removed.
+func (ed *ExecDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	ReportProvider(ctx, ProviderExec)
+	stdin, err := json.Marshal(execDialogInput{Prompt: prompt, Title: title})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal prompt for command: %w", err)
+	}
+
+	shellName, args := shell.Command(ed.Command)
+	cmd := exec.CommandContext(ctx, shellName, args...)
+	cmd.Env = append(os.Environ(), "USER_PROMPT_TITLE="+title, "USER_PROMPT_TEXT="+prompt)
+	cmd.Stdin = bytes.NewReader(append(stdin, '\n'))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = execWaitDelay
+
+	slog.Debug("Running prompt command", "component", "exec_dialog", "command", ed.Command)
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("prompt command was cancelled: %w", ctxErr)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("prompt command exited with status %d: %s", exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("failed to run prompt command: %w", err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// SupportsConcurrentPrompts implements ConcurrentDialogProvider.
+func (ed *ExecDialog) SupportsConcurrentPrompts() bool {
+	return true // Every prompt runs its own process
+}
+
+// CheckDependencies verifies that a command is configured and that the
+// system shell used to run it is available.
+func (ed *ExecDialog) CheckDependencies() error {
+	if strings.TrimSpace(ed.Command) == "" {
+		return errors.New("no prompt command configured")
+	}
+	shellName, _ := shell.Command(ed.Command)
+	if _, err := exec.LookPath(shellName); err != nil {
+		return fmt.Errorf("shell %q for prompt command not found: %w", shellName, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package gui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecDialog_ShowInputDialog(t *testing.T) {
+	// Answer from the environment variables
+	dialog := NewExecDialog(`printf '%s: %s\n' "$USER_PROMPT_TITLE" "$USER_PROMPT_TEXT"`)
+	result, err := dialog.ShowInputDialog(context.Background(), "Continue?", "Question")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result != "Question: Continue?" {
+		t.Errorf("Expected result 'Question: Continue?', got: %q", result)
+	}
+
+	// Answer from the JSON on stdin
+	dialog = NewExecDialog("cat")
+	result, err = dialog.ShowInputDialog(context.Background(), "Continue?", "Question")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result != `{"prompt":"Continue?","title":"Question"}` {
+		t.Errorf("Expected the prompt JSON on stdin, got: %q", result)
+	}
+}
+
+func TestExecDialog_NonZeroExit(t *testing.T) {
+	dialog := NewExecDialog("echo cancelled by user >&2; exit 1")
+	_, err := dialog.ShowInputDialog(context.Background(), "Continue?", "Question")
+	if err == nil {
+		t.Fatal("Expected error for non-zero exit status, got nil")
+	}
+	if !strings.Contains(err.Error(), "status 1") || !strings.Contains(err.Error(), "cancelled by user") {
+		t.Errorf("Expected exit status and stderr in error, got: %v", err)
+	}
+}
+
+func TestExecDialog_ContextCancellation(t *testing.T) {
+	// The child sleep keeps stdout open, so this only returns promptly if
+	// the whole process group is killed.
+	dialog := NewExecDialog("sleep 30; echo too late")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := dialog.ShowInputDialog(ctx, "Continue?", "Question")
+	if err == nil {
+		t.Fatal("Expected cancellation error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > execWaitDelay {
+		t.Errorf("Expected command to be killed promptly, took %v", elapsed)
+	}
+}
+
+func TestExecDialog_CheckDependencies(t *testing.T) {
+	if err := NewExecDialog("").CheckDependencies(); err == nil {
+		t.Error("Expected error for empty command, got nil")
+	}
+	if err := NewExecDialog("true").CheckDependencies(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
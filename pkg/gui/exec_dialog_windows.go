@@ -0,0 +1,14 @@
+//go:build windows
+
+package gui
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no POSIX process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,49 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// FallbackDialog implements DialogProvider by trying a primary provider and
+// switching to a fallback provider when the primary reports that no UI is
+// connected to show the prompt (ErrNoUIConnected).
+type FallbackDialog struct {
+	Primary  DialogProvider
+	Fallback DialogProvider
+}
+
+// NewFallbackDialog creates a new FallbackDialog.
+func NewFallbackDialog(primary, fallback DialogProvider) *FallbackDialog {
+	return &FallbackDialog{Primary: primary, Fallback: fallback}
+}
+
+// ShowInputDialog shows the prompt with the primary provider, or with the
+// fallback provider if no UI is connected.
+func (fd *FallbackDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	result, err := fd.Primary.ShowInputDialog(ctx, prompt, title)
+	if !errors.Is(err, ErrNoUIConnected) {
+		return result, err
+	}
+
+	slog.Info("Using the fallback provider", "component", "fallback_dialog", "error", err)
+	ReportStatus(ctx, "no UI connected, using fallback provider")
+	result, fallbackErr := fd.Fallback.ShowInputDialog(ctx, prompt, title)
+	if fallbackErr != nil {
+		return "", fmt.Errorf("%v; fallback provider failed: %w", err, fallbackErr)
+	}
+	return result, nil
+}
+
+// CheckDependencies checks both providers.
+func (fd *FallbackDialog) CheckDependencies() error {
+	if err := fd.Primary.CheckDependencies(); err != nil {
+		return err
+	}
+	if err := fd.Fallback.CheckDependencies(); err != nil {
+		return fmt.Errorf("fallback provider: %w", err)
+	}
+	return nil
+}
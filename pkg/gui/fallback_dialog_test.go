@@ -0,0 +1,40 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// errorDialog is a DialogProvider that always fails with the same error.
+type errorDialog struct {
+	err error
+}
+
+func (d *errorDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	return "", d.err
+}
+
+func (d *errorDialog) CheckDependencies() error {
+	return nil
+}
+
+func TestFallbackDialog_NoUIConnected(t *testing.T) {
+	primary := &errorDialog{err: fmt.Errorf("%w: open the Vibeframe panel", ErrNoUIConnected)}
+	dialog := NewFallbackDialog(primary, &staticDialog{answer: "fallback answer"})
+
+	result, err := dialog.ShowInputDialog(context.Background(), "Prompt", "Title")
+	if err != nil || result != "fallback answer" {
+		t.Errorf("Expected fallback answer, got: %q, %v", result, err)
+	}
+}
+
+func TestFallbackDialog_OtherErrors(t *testing.T) {
+	primaryErr := errors.New("timed out")
+	dialog := NewFallbackDialog(&errorDialog{err: primaryErr}, &staticDialog{answer: "fallback answer"})
+
+	if _, err := dialog.ShowInputDialog(context.Background(), "Prompt", "Title"); !errors.Is(err, primaryErr) {
+		t.Errorf("Expected primary error without fallback, got: %v", err)
+	}
+}
@@ -4,8 +4,31 @@ import (
 	"context"
 )
 
+// Names of the dialog providers, as reported by ReportProvider.
+const (
+	ProviderRemote = "remote"
+	ProviderExec   = "exec"
+	ProviderSpool  = "spool"
+	ProviderScript = "script"
+)
+
 // DialogProvider defines the interface for displaying user input dialogs
 type DialogProvider interface {
 	ShowInputDialog(ctx context.Context, prompt string, title string) (string, error)
 	CheckDependencies() error
 }
+
+// ConcurrentDialogProvider is implemented by providers that can show more
+// than one prompt at the same time.
+type ConcurrentDialogProvider interface {
+	DialogProvider
+	SupportsConcurrentPrompts() bool
+}
+
+// SupportsConcurrentPrompts reports whether the provider can show several
+// prompts at once. Providers that do not implement ConcurrentDialogProvider
+// are assumed to show one prompt at a time.
+func SupportsConcurrentPrompts(dialog DialogProvider) bool {
+	concurrent, ok := dialog.(ConcurrentDialogProvider)
+	return ok && concurrent.SupportsConcurrentPrompts()
+}
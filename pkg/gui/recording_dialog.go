@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Outcomes of a recorded prompt.
+const (
+	OutcomeAnswered  = "answered"
+	OutcomeError     = "error"
+	OutcomeTimedOut  = "timed_out"
+	OutcomeCancelled = "cancelled"
+)
+
+// PromptRecord is one prompt/answer pair as written by RecordingDialog.
+type PromptRecord struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Prompt     string    `json:"prompt"`
+	Title      string    `json:"title"`
+	Outcome    string    `json:"outcome"`
+	Answer     string    `json:"answer,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// newPromptRecord builds the record of a finished ShowInputDialog call.
+func newPromptRecord(ctx context.Context, start time.Time, prompt, title, answer string, err error) PromptRecord {
+	record := PromptRecord{
+		StartedAt:  start.UTC(),
+		DurationMs: time.Since(start).Milliseconds(),
+		Prompt:     prompt,
+		Title:      title,
+		Outcome:    OutcomeAnswered,
+		Answer:     answer,
+	}
+	if err != nil {
+		record.Answer = ""
+		record.Error = err.Error()
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			record.Outcome = OutcomeTimedOut
+		case ctx.Err() != nil:
+			record.Outcome = OutcomeCancelled
+		default:
+			record.Outcome = OutcomeError
+		}
+	}
+	return record
+}
+
+// ScriptStep converts the record into a script step that replays its
+// outcome. The answer is given right away rather than after DurationMs, so
+// replayed tests do not wait for a human's pace.
+func (r PromptRecord) ScriptStep() ScriptStep {
+	step := ScriptStep{
+		Prompt: "^" + regexp.QuoteMeta(r.Prompt) + "$",
+		Title:  "^" + regexp.QuoteMeta(r.Title) + "$",
+	}
+	switch r.Outcome {
+	case OutcomeAnswered:
+		step.Answer = r.Answer
+	case OutcomeTimedOut, OutcomeCancelled:
+		step.Timeout = true
+	default:
+		step.Error = r.Error
+	}
+	return step
+}
+
+// LoadRecording reads the prompt records of a JSONL recording.
+func LoadRecording(path string) ([]PromptRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []PromptRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record PromptRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d of %s: %w", line, path, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// RecordingDialog wraps another DialogProvider and appends every prompt,
+// its outcome and its timing as a JSON line to a file, independent of which
+// provider answers. A recording can be replayed with ScriptDialog.
+type RecordingDialog struct {
+	dialog DialogProvider
+	path   string
+	mu     sync.Mutex
+}
+
+// NewRecordingDialog creates a new RecordingDialog appending to path.
+func NewRecordingDialog(dialog DialogProvider, path string) *RecordingDialog {
+	return &RecordingDialog{dialog: dialog, path: path}
+}
+
+// ShowInputDialog delegates to the wrapped provider and records the result.
+func (rd *RecordingDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	start := time.Now()
+	result, err := rd.dialog.ShowInputDialog(ctx, prompt, title)
+
+	if writeErr := rd.append(newPromptRecord(ctx, start, prompt, title, result, err)); writeErr != nil {
+		slog.Error("Failed to write prompt recording", "component", "recording_dialog", "path", rd.path, "error", writeErr)
+	}
+	return result, err
+}
+
+func (rd *RecordingDialog) append(record PromptRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	file, err := os.OpenFile(rd.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// SupportsConcurrentPrompts implements ConcurrentDialogProvider.
+func (rd *RecordingDialog) SupportsConcurrentPrompts() bool {
+	return SupportsConcurrentPrompts(rd.dialog)
+}
+
+// CheckDependencies checks the wrapped provider and that the recording
+// file can be opened for appending.
+func (rd *RecordingDialog) CheckDependencies() error {
+	if err := rd.dialog.CheckDependencies(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(rd.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open recording file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,69 @@
+package gui
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordingDialog_RecordsOutcomes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	dialog := NewRecordingDialog(&staticDialog{answer: "yes"}, path)
+
+	if _, err := dialog.ShowInputDialog(context.Background(), "Proceed?", "Question"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	neverAnswers, err := NewScriptDialog([]ScriptStep{{Prompt: ".*", Timeout: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timedOut := NewRecordingDialog(neverAnswers, path)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := timedOut.ShowInputDialog(ctx, "Still there?", ""); err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+
+	records, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Outcome != OutcomeAnswered || records[0].Answer != "yes" || records[0].Prompt != "Proceed?" {
+		t.Errorf("Unexpected first record: %+v", records[0])
+	}
+	if records[1].Outcome != OutcomeTimedOut || records[1].Error == "" {
+		t.Errorf("Unexpected second record: %+v", records[1])
+	}
+}
+
+func TestRecordingDialog_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder := NewRecordingDialog(&staticDialog{answer: "recorded answer"}, path)
+	if _, err := recorder.ShowInputDialog(context.Background(), "What (now)?", "Title"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	replay, err := NewScriptDialogFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load recording as script: %v", err)
+	}
+	result, err := replay.ShowInputDialog(context.Background(), "What (now)?", "Title")
+	if err != nil || result != "recorded answer" {
+		t.Errorf("Expected recorded answer, got: %q, %v", result, err)
+	}
+	if err := replay.Err(); err != nil {
+		t.Errorf("Expected recording to be fully replayed, got: %v", err)
+	}
+}
+
+func TestPromptRecord_ScriptStepAnswersRightAway(t *testing.T) {
+	record := PromptRecord{DurationMs: 60000, Prompt: "Slow?", Outcome: OutcomeAnswered, Answer: "eventually"}
+	if step := record.ScriptStep(); step.Delay != 0 || step.Answer != "eventually" {
+		t.Errorf("Expected the answer without the recorded delay, got %+v", step)
+	}
+}
@@ -7,41 +7,162 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RemoteDialog implements DialogProvider by making HTTP calls to a separate server.
 type RemoteDialog struct {
-	ServerURL string // e.g., "http://localhost:3030"
+	// ServerURL is e.g. "http://localhost:3030", or a Unix domain socket
+	// such as "unix:///run/user/1000/user-prompt.sock".
+	ServerURL string
 	Client    *http.Client
+	// StatusPollInterval is how often the prompt's presence status is polled
+	// while waiting, if a status reporter is attached to the context.
+	StatusPollInterval time.Duration
+	// NoUIPolicy overrides the server's policy for prompts nobody can see:
+	// "wait" keeps waiting for the prompt timeout, "fail" fails with
+	// ErrNoUIConnected after NoUIGraceMs. Empty uses the server's setting.
+	NoUIPolicy  string
+	NoUIGraceMs int64
+	// Launcher, if set, starts the server when it is not running.
+	Launcher *ServerLauncher
+	// ConflictRetryInterval, if positive, makes a prompt rejected because
+	// the server is showing another one wait for its turn, retrying at this
+	// interval until the prompt times out. Clients sharing a server need it,
+	// as the server shows one prompt at a time. Zero fails with
+	// ErrPromptConflict instead.
+	ConflictRetryInterval time.Duration
+	// TracerProvider and Propagator trace the request and pass its context
+	// on to the server; nil uses the global ones at span start.
+	TracerProvider trace.TracerProvider
+	Propagator     propagation.TextMapPropagator
 }
 
+const tracerName = "github.com/nazar256/user-prompt-mcp/pkg/gui"
+
+// ErrNoUIConnected is returned when the server reports that no UI client is
+// connected to show the prompt.
+var ErrNoUIConnected = errors.New("no UI connected to the prompt server")
+
+// ErrPromptConflict is returned when the server is showing another prompt,
+// e.g. one sent by another client sharing the server.
+var ErrPromptConflict = errors.New("another prompt is shown on the prompt server")
+
+// ErrPromptDismissed is returned when the user dismissed the prompt without
+// answering it.
+var ErrPromptDismissed = errors.New("prompt dismissed by the user")
+
+// socketBaseURL is the base URL of requests sent over a Unix domain socket;
+// the host is ignored by the socket's dialer.
+const socketBaseURL = "http://unix"
+
 // NewRemoteDialog creates a new RemoteDialog.
-// serverURL should be the base URL of the user-prompt-server (e.g., "http://localhost:3030").
+// serverURL should be the base URL of the user-prompt-server (e.g., "http://localhost:3030"),
+// or a "unix://" URL of the socket it listens on.
 func NewRemoteDialog(serverURL string) *RemoteDialog {
+	client := &http.Client{
+		Timeout: 0, // Context will handle overall timeout for the request
+	}
+	if path, ok := protocol.SocketPath(serverURL); ok {
+		var dialer net.Dialer
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		}
+	}
 	return &RemoteDialog{
-		ServerURL: serverURL,
-		Client: &http.Client{
-			Timeout: 0, // Context will handle overall timeout for the request
-		},
+		ServerURL:          serverURL,
+		Client:             client,
+		StatusPollInterval: 2 * time.Second,
 	}
 }
 
-type TriggerPromptRequest struct {
-	Prompt    string `json:"prompt"`
-	Title     string `json:"title"`
-	TimeoutMs int64  `json:"timeout_ms"`
+// tracer returns the tracer for the dialog's spans.
+func (rd *RemoteDialog) tracer() trace.Tracer {
+	if rd.TracerProvider != nil {
+		return rd.TracerProvider.Tracer(tracerName)
+	}
+	return otel.Tracer(tracerName)
 }
 
-type TriggerPromptResponse struct {
-	Input string `json:"input,omitempty"`
-	Error string `json:"error,omitempty"`
+// propagator returns the propagator passing the trace context to the server.
+func (rd *RemoteDialog) propagator() propagation.TextMapPropagator {
+	if rd.Propagator != nil {
+		return rd.Propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// endpoint returns the URL of path on the server.
+func (rd *RemoteDialog) endpoint(path string) string {
+	if _, ok := protocol.SocketPath(rd.ServerURL); ok {
+		return socketBaseURL + path
+	}
+	return rd.ServerURL + path
 }
 
 // ShowInputDialog sends a prompt request to the remote server and waits for the response.
-func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (result string, err error) {
+	ReportProvider(ctx, ProviderRemote)
+	log := slog.With("component", "remote_dialog", "server_url", rd.ServerURL)
+	ctx, span := rd.tracer().Start(ctx, "RemoteDialog.ShowInputDialog",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.url", rd.ServerURL)))
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if rd.Launcher != nil && !rd.healthy(ctx) {
+		log.Info("Server is not running, starting it")
+		ReportStatus(ctx, "starting user-prompt-server")
+		if err := rd.Launcher.Start(ctx, rd.healthy); err != nil {
+			return "", fmt.Errorf("failed to start user-prompt-server: %w", err)
+		}
+	}
+
+	// Use the caller's prompt ID, so the server's prompt matches its history
+	promptID := PromptID(ctx)
+	if promptID == "" {
+		promptID = uuid.NewString()
+	}
+	log = log.With("prompt_id", promptID)
+	span.SetAttributes(attribute.String("prompt.id", promptID))
+
+	for waiting := false; ; waiting = true {
+		answer, err := rd.trigger(ctx, span, log, promptID, prompt, title)
+		if !errors.Is(err, ErrPromptConflict) || rd.ConflictRetryInterval <= 0 {
+			return answer, err
+		}
+		if !waiting {
+			log.Info("Another prompt is shown on the server, waiting for it to finish")
+			ReportStatus(ctx, "waiting for another client's prompt to finish")
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("prompt cancelled while waiting for another client's prompt: %w", ctx.Err())
+		case <-time.After(rd.ConflictRetryInterval):
+		}
+	}
+}
+
+// trigger sends the prompt to the server once and waits for the response.
+func (rd *RemoteDialog) trigger(ctx context.Context, span trace.Span, log *slog.Logger, promptID, prompt, title string) (string, error) {
 	var timeoutMs int64
 	if deadline, ok := ctx.Deadline(); ok {
 		remaining := time.Until(deadline)
@@ -53,38 +174,48 @@ func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, titl
 		}
 	} else {
 		timeoutMs = (20 * time.Minute).Milliseconds() // Default if no deadline on context
-		log.Println("Warning: No deadline on context for RemoteDialog.ShowInputDialog, using default timeout for server call.")
+		log.Warn("No deadline on the prompt context, using the default timeout for the server call", "timeout_ms", timeoutMs)
 	}
 
 	if timeoutMs <= 0 { // Ensure we don't send a non-positive timeout
 		return "", fmt.Errorf("prompt context resulted in non-positive timeout: %dms", timeoutMs)
 	}
 
-	requestPayload := TriggerPromptRequest{
-		Prompt:    prompt,
-		Title:     title,
-		TimeoutMs: timeoutMs,
+	requestPayload := protocol.TriggerPromptRequest{
+		ID:          promptID,
+		Prompt:      prompt,
+		Title:       title,
+		TimeoutMs:   timeoutMs,
+		NoUIPolicy:  rd.NoUIPolicy,
+		NoUIGraceMs: rd.NoUIGraceMs,
+		Queued:      QueueLength(ctx),
 	}
 
 	payloadBytes, err := json.Marshal(requestPayload)
 	if err != nil {
-		log.Printf("RemoteDialog: Error marshalling request: %v", err)
 		return "", fmt.Errorf("failed to marshal prompt request: %w", err)
 	}
 
-	reqURL := rd.ServerURL + "/api/trigger-prompt"
-	log.Printf("RemoteDialog: Sending prompt request to %s with timeout %dms", reqURL, timeoutMs)
+	reqURL := rd.endpoint("/api/trigger-prompt")
+	log.Debug("Sending prompt request", "timeout_ms", timeoutMs)
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		log.Printf("RemoteDialog: Error creating HTTP request: %v", err)
 		return "", fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	// Continue the trace in the server's wait span
+	rd.propagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	if hasStatusReporter(ctx) && rd.StatusPollInterval > 0 {
+		pollCtx, stopPolling := context.WithCancel(ctx)
+		defer stopPolling()
+		go rd.pollStatus(pollCtx, requestPayload.ID)
+	}
 
 	httpResp, err := rd.Client.Do(httpReq)
 	if err != nil {
-		log.Printf("RemoteDialog: Error sending HTTP request to server: %v", err)
+		log.Warn("Prompt request failed", "error", err)
 		// Check if context error is the cause
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 			return "", fmt.Errorf("prompt request to server timed out or was cancelled: %w", err)
@@ -95,15 +226,16 @@ func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, titl
 
 	bodyBytes, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		log.Printf("RemoteDialog: Error reading response body: %v", err)
+		log.Warn("Failed to read the server's response", "error", err)
 		return "", fmt.Errorf("failed to read response from server: %w", err)
 	}
 
-	log.Printf("RemoteDialog: Received response from server: Status=%s, Body=%s", httpResp.Status, string(bodyBytes))
+	log.Debug("Received response from server", "status", httpResp.StatusCode)
+	span.SetAttributes(attribute.Int("http.response.status_code", httpResp.StatusCode))
 
-	var serverResponse TriggerPromptResponse
+	var serverResponse protocol.TriggerPromptResponse
 	if err := json.Unmarshal(bodyBytes, &serverResponse); err != nil {
-		log.Printf("RemoteDialog: Error unmarshalling server response: %v. Body: %s", err, string(bodyBytes))
+		log.Warn("Failed to decode the server's response", "status", httpResp.StatusCode, "error", err)
 		// If unmarshalling fails, but status was OK, it's an issue.
 		// If status was not OK, the error might be in plain text or non-JSON.
 		if httpResp.StatusCode == http.StatusOK {
@@ -114,7 +246,18 @@ func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, titl
 	}
 
 	if httpResp.StatusCode != http.StatusOK {
+		switch serverResponse.Code {
+		case protocol.ErrorCodeNoUIConnected:
+			return "", fmt.Errorf("%w: %s", ErrNoUIConnected, serverResponse.Error)
+		case protocol.ErrorCodeDismissed:
+			return "", fmt.Errorf("%w: %s", ErrPromptDismissed, serverResponse.Error)
+		case protocol.ErrorCodePromptConflict:
+			return "", fmt.Errorf("%w: %s", ErrPromptConflict, serverResponse.Error)
+		}
 		if serverResponse.Error != "" {
+			if serverResponse.Presence != nil {
+				return "", fmt.Errorf("server error: %s (status %s, %s)", serverResponse.Error, httpResp.Status, serverResponse.Presence.Describe())
+			}
 			return "", fmt.Errorf("server error: %s (status %s)", serverResponse.Error, httpResp.Status)
 		}
 		return "", fmt.Errorf("server returned non-OK status: %s, with body: %s", httpResp.Status, string(bodyBytes))
@@ -128,6 +271,58 @@ func (rd *RemoteDialog) ShowInputDialog(ctx context.Context, prompt string, titl
 	return serverResponse.Input, nil
 }
 
+// pollStatus reports the prompt's presence status through gui.ReportStatus
+// whenever it changes, until ctx is done.
+func (rd *RemoteDialog) pollStatus(ctx context.Context, promptID string) {
+	ticker := time.NewTicker(rd.StatusPollInterval)
+	defer ticker.Stop()
+
+	statusURL := rd.endpoint("/api/prompts/" + url.PathEscape(promptID) + "/status")
+	var last string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+		if err != nil {
+			return
+		}
+		resp, err := rd.Client.Do(req)
+		if err != nil {
+			continue
+		}
+		var presence protocol.PromptPresence
+		err = json.NewDecoder(resp.Body).Decode(&presence)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue // Not registered yet or an older server without status support
+		}
+		if status := presence.Describe(); status != last {
+			last = status
+			ReportStatus(ctx, status)
+		}
+	}
+}
+
+// healthy reports whether the server answers its health check.
+func (rd *RemoteDialog) healthy(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rd.endpoint("/api/health"), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := rd.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
 // CheckDependencies for RemoteDialog - none needed as it's network-based.
 func (rd *RemoteDialog) CheckDependencies() error {
 	// Could add a ping to the server here if desired
@@ -0,0 +1,215 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRemoteDialogUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user-prompt.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/trigger-prompt", func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.TriggerPromptRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "answer to " + req.Prompt})
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	answer, err := NewRemoteDialog("unix://"+path).ShowInputDialog(ctx, "Continue?", "Test")
+	if err != nil {
+		t.Fatalf("ShowInputDialog failed: %v", err)
+	}
+	if answer != "answer to Continue?" {
+		t.Errorf("Expected the server's answer, got %q", answer)
+	}
+}
+
+func TestRemoteDialogSendsPromptIDAndQueueLength(t *testing.T) {
+	requests := make(chan protocol.TriggerPromptRequest, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req protocol.TriggerPromptRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests <- req
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "yes"})
+	}))
+	defer ts.Close()
+
+	ctx := WithQueueLength(WithPromptID(context.Background(), "caller-id"), func() int { return 2 })
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := NewRemoteDialog(ts.URL).ShowInputDialog(ctx, "Continue?", "Test"); err != nil {
+		t.Fatalf("ShowInputDialog failed: %v", err)
+	}
+	if req := <-requests; req.ID != "caller-id" || req.Queued != 2 {
+		t.Errorf("Expected the caller's prompt ID and queue length, got %q and %d", req.ID, req.Queued)
+	}
+}
+
+func TestRemoteDialogMapsErrorCodes(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{protocol.ErrorCodeNoUIConnected, ErrNoUIConnected},
+		{protocol.ErrorCodeDismissed, ErrPromptDismissed},
+		{protocol.ErrorCodePromptConflict, ErrPromptConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Error: "not now", Code: tt.code})
+			}))
+			defer ts.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := NewRemoteDialog(ts.URL).ShowInputDialog(ctx, "Continue?", "Test")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRemoteDialogWaitsForConflictingPrompt(t *testing.T) {
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Error: "another prompt is active", Code: protocol.ErrorCodePromptConflict})
+			return
+		}
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "yes"})
+	}))
+	defer ts.Close()
+
+	var reported []string
+	ctx := WithStatusReporter(context.Background(), func(status string) {
+		reported = append(reported, status)
+	})
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rd := NewRemoteDialog(ts.URL)
+	rd.StatusPollInterval = 0
+	rd.ConflictRetryInterval = 5 * time.Millisecond
+	answer, err := rd.ShowInputDialog(ctx, "Continue?", "Test")
+	if err != nil || answer != "yes" {
+		t.Fatalf("Expected the answer after the other prompt, got %q and error %v", answer, err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+	if len(reported) != 1 || reported[0] != "waiting for another client's prompt to finish" {
+		t.Errorf("Expected one waiting status, got %q", reported)
+	}
+}
+
+func TestRemoteDialogReportsStatusChanges(t *testing.T) {
+	// Statuses served in turn; nil stands for a prompt that is not
+	// registered yet. The last one is repeated.
+	statuses := []*protocol.PromptPresence{
+		nil,
+		{UIClients: 1},
+		{UIClients: 1},
+		{UIClients: 1, Delivered: true, Viewed: true},
+		{UIClients: 1, Delivered: true, Viewed: true, Typing: true},
+		{UIClients: 1, Delivered: true, Viewed: true, Typing: true},
+	}
+	served := make(chan struct{})
+	var polls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/prompts/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "p1" {
+			t.Errorf("Expected the status of p1, got %s", r.PathValue("id"))
+		}
+		status := statuses[min(polls, len(statuses)-1)]
+		if polls++; polls == len(statuses) {
+			close(served)
+		}
+		if status == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	mux.HandleFunc("POST /api/trigger-prompt", func(w http.ResponseWriter, r *http.Request) {
+		<-served
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "yes"})
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	var reported []string
+	ctx := WithStatusReporter(WithPromptID(context.Background(), "p1"), func(status string) {
+		reported = append(reported, status)
+	})
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rd := NewRemoteDialog(ts.URL)
+	rd.StatusPollInterval = 5 * time.Millisecond
+	if _, err := rd.ShowInputDialog(ctx, "Continue?", "Test"); err != nil {
+		t.Fatalf("ShowInputDialog failed: %v", err)
+	}
+
+	expected := []string{"user has not seen the prompt yet", "user has seen the prompt", "user is typing"}
+	if strings.Join(reported, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("Expected only the changes %q, got %q", expected, reported)
+	}
+}
+
+func TestRemoteDialogPropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	traceparent := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent <- r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "yes"})
+	}))
+	defer ts.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "agent")
+	rd := NewRemoteDialog(ts.URL)
+	rd.TracerProvider, rd.Propagator = provider, propagation.TraceContext{}
+	if _, err := rd.ShowInputDialog(ctx, "Continue?", "Test"); err != nil {
+		t.Fatalf("ShowInputDialog failed: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "RemoteDialog.ShowInputDialog" {
+		t.Fatalf("Expected the dialog span followed by its parent, got %d spans", len(spans))
+	}
+	dialog := spans[0]
+	if dialog.Parent().SpanID() != parent.SpanContext().SpanID() || dialog.SpanKind() != trace.SpanKindClient {
+		t.Errorf("Expected a client span under the caller's span, got kind %v with parent %v", dialog.SpanKind(), dialog.Parent().SpanID())
+	}
+	expected := "00-" + dialog.SpanContext().TraceID().String() + "-" + dialog.SpanContext().SpanID().String() + "-01"
+	if got := <-traceparent; got != expected {
+		t.Errorf("Expected traceparent %q, got %q", expected, got)
+	}
+}
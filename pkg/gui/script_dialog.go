@@ -0,0 +1,200 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that is written as a string such as "1.5s"
+// in script files.
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ScriptStep is one expected prompt in a script and how to answer it.
+type ScriptStep struct {
+	// Prompt is a regular expression the prompt text must match.
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// Title is an optional regular expression the title must match.
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Answer is returned as the user's input.
+	Answer string `json:"answer,omitempty" yaml:"answer,omitempty"`
+	// Error, if set, fails the prompt with this message instead of answering.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Delay is how long to wait before answering.
+	Delay Duration `json:"delay,omitempty" yaml:"delay,omitempty"`
+	// Timeout simulates a user who never answers: the prompt blocks until
+	// its context is done.
+	Timeout bool `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// LoadScript reads a list of script steps from a YAML or JSON file. Files
+// with a .json extension are parsed as JSON, .jsonl files are read as a
+// RecordingDialog recording, and everything else is parsed as YAML.
+func LoadScript(path string) ([]ScriptStep, error) {
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		records, err := LoadRecording(path)
+		if err != nil {
+			return nil, err
+		}
+		steps := make([]ScriptStep, len(records))
+		for i, record := range records {
+			steps[i] = record.ScriptStep()
+		}
+		return steps, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []ScriptStep
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &steps)
+	} else {
+		err = yaml.Unmarshal(data, &steps)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+	return steps, nil
+}
+
+type compiledStep struct {
+	ScriptStep
+	prompt *regexp.Regexp
+	title  *regexp.Regexp
+}
+
+// ScriptDialog implements DialogProvider by answering prompts from a
+// script, for deterministic tests of agents that use user_prompt. Real
+// sessions are captured for replay with RecordingDialog.
+//
+// Steps are consumed in order. A prompt that does not match the next step,
+// or that arrives after the script is exhausted, fails with an error and is
+// recorded so that Err reports it at the end of the test.
+type ScriptDialog struct {
+	mu       sync.Mutex
+	steps    []compiledStep
+	next     int
+	failures []error
+}
+
+// NewScriptDialog creates a new ScriptDialog from the given steps.
+func NewScriptDialog(steps []ScriptStep) (*ScriptDialog, error) {
+	compiled := make([]compiledStep, len(steps))
+	for i, step := range steps {
+		var err error
+		compiled[i].ScriptStep = step
+		if compiled[i].prompt, err = regexp.Compile(step.Prompt); err != nil {
+			return nil, fmt.Errorf("step %d: invalid prompt pattern: %w", i+1, err)
+		}
+		if step.Title != "" {
+			if compiled[i].title, err = regexp.Compile(step.Title); err != nil {
+				return nil, fmt.Errorf("step %d: invalid title pattern: %w", i+1, err)
+			}
+		}
+	}
+	return &ScriptDialog{steps: compiled}, nil
+}
+
+// NewScriptDialogFromFile creates a new ScriptDialog from a script file.
+func NewScriptDialogFromFile(path string) (*ScriptDialog, error) {
+	steps, err := LoadScript(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptDialog(steps)
+}
+
+// ShowInputDialog answers the prompt from the next script step.
+func (sd *ScriptDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	ReportProvider(ctx, ProviderScript)
+	step, err := sd.take(prompt, title)
+	if err != nil {
+		slog.Warn("Prompt does not match the script", "component", "script_dialog", "error", err)
+		return "", err
+	}
+
+	if step.Timeout {
+		<-ctx.Done()
+		return "", fmt.Errorf("scripted prompt timed out: %w", ctx.Err())
+	}
+	if step.Delay > 0 {
+		timer := time.NewTimer(time.Duration(step.Delay))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("scripted prompt was cancelled during delay: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if step.Error != "" {
+		return "", errors.New(step.Error)
+	}
+	return step.Answer, nil
+}
+
+// take matches the prompt against the next step and consumes it.
+func (sd *ScriptDialog) take(prompt, title string) (compiledStep, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	var err error
+	switch {
+	case sd.next >= len(sd.steps):
+		err = fmt.Errorf("unexpected prompt %q: script has no more steps", prompt)
+	case !sd.steps[sd.next].prompt.MatchString(prompt):
+		err = fmt.Errorf("unexpected prompt %q: step %d expects prompt matching %q", prompt, sd.next+1, sd.steps[sd.next].Prompt)
+	case sd.steps[sd.next].title != nil && !sd.steps[sd.next].title.MatchString(title):
+		err = fmt.Errorf("unexpected title %q: step %d expects title matching %q", title, sd.next+1, sd.steps[sd.next].Title)
+	}
+	if err != nil {
+		sd.failures = append(sd.failures, err)
+		return compiledStep{}, err
+	}
+	sd.next++
+	return sd.steps[sd.next-1], nil
+}
+
+// Err reports every unexpected prompt seen so far and any steps that were
+// never reached. Tests should check it once the agent has finished.
+func (sd *ScriptDialog) Err() error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	errs := append([]error(nil), sd.failures...)
+	if remaining := len(sd.steps) - sd.next; remaining > 0 {
+		errs = append(errs, fmt.Errorf("%d script step(s) never reached, next expects prompt matching %q", remaining, sd.steps[sd.next].Prompt))
+	}
+	return errors.Join(errs...)
+}
+
+// CheckDependencies for ScriptDialog - none needed.
+func (sd *ScriptDialog) CheckDependencies() error {
+	return nil
+}
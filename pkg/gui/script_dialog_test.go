@@ -0,0 +1,93 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScriptDialog_ReplaysSteps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	script := `
+- prompt: "(?i)proceed"
+  answer: "yes"
+- prompt: "deploy"
+  title: "^Confirm$"
+  error: "denied"
+  delay: 10ms
+`
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dialog, err := NewScriptDialogFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load script: %v", err)
+	}
+
+	result, err := dialog.ShowInputDialog(context.Background(), "Should I PROCEED?", "Question")
+	if err != nil || result != "yes" {
+		t.Errorf("Expected 'yes', got: %q, %v", result, err)
+	}
+
+	start := time.Now()
+	_, err = dialog.ShowInputDialog(context.Background(), "deploy to prod?", "Confirm")
+	if err == nil || err.Error() != "denied" {
+		t.Errorf("Expected scripted error 'denied', got: %v", err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("Expected scripted delay before answering")
+	}
+
+	if err := dialog.Err(); err != nil {
+		t.Errorf("Expected script to be fully consumed, got: %v", err)
+	}
+}
+
+func TestScriptDialog_UnexpectedPrompt(t *testing.T) {
+	dialog, err := NewScriptDialog([]ScriptStep{{Prompt: "^first$", Answer: "1"}, {Prompt: "^second$", Answer: "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dialog.ShowInputDialog(context.Background(), "other", ""); err == nil {
+		t.Error("Expected error for unexpected prompt, got nil")
+	}
+	if _, err := dialog.ShowInputDialog(context.Background(), "first", ""); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	// One unexpected prompt and one step never reached
+	if err := dialog.Err(); err == nil {
+		t.Error("Expected Err to report the unexpected prompt and the remaining step")
+	}
+}
+
+func TestScriptDialog_Timeout(t *testing.T) {
+	dialog, err := NewScriptDialog([]ScriptStep{{Prompt: ".*", Timeout: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = dialog.ShowInputDialog(ctx, "anything", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+}
+
+// staticDialog is a DialogProvider that always returns the same answer.
+type staticDialog struct {
+	answer string
+}
+
+func (d *staticDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	return d.answer, nil
+}
+
+func (d *staticDialog) CheckDependencies() error {
+	return nil
+}
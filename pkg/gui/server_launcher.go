@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerLauncher starts user-prompt-server in the background for a
+// RemoteDialog when it is not running. Clients sharing a StateDir share one
+// server: a lock file makes sure that only one of them starts it, and the
+// others wait for it to come up.
+type ServerLauncher struct {
+	// Command and Args start the server. The launcher adds --pid-file.
+	Command string
+	Args    []string
+	// StateDir holds the lock file, the server's PID file and its log.
+	StateDir string
+	// StartTimeout is how long to wait for a started server to become
+	// healthy.
+	StartTimeout time.Duration
+}
+
+// The environment variables of settings share this prefix between
+// user-prompt-mcp and user-prompt-server, e.g. USER_PROMPT_LOG_FILE.
+const (
+	settingsEnvPrefix = "USER_PROMPT_"
+	configEnv         = settingsEnvPrefix + "CONFIG"
+)
+
+// Files in ServerLauncher.StateDir.
+const (
+	launcherLockFile = "server.lock"
+	launcherPIDFile  = "server.pid"
+	launcherLogFile  = "server.log"
+)
+
+// NewServerLauncher creates a new ServerLauncher.
+func NewServerLauncher(command string, args []string, stateDir string) *ServerLauncher {
+	return &ServerLauncher{
+		Command:      command,
+		Args:         args,
+		StateDir:     stateDir,
+		StartTimeout: 10 * time.Second,
+	}
+}
+
+// DefaultLauncherStateDir returns user-prompt-mcp in the user's cache
+// directory.
+func DefaultLauncherStateDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "user-prompt-mcp"), nil
+}
+
+// Start starts the server, unless healthy reports that one is already
+// running, and waits until healthy reports that it is up.
+func (l *ServerLauncher) Start(ctx context.Context, healthy func(context.Context) bool) error {
+	if err := os.MkdirAll(l.StateDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.StartTimeout)
+	defer cancel()
+
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if healthy(ctx) {
+		return nil // Started by another client while we waited for the lock
+	}
+
+	logPath := filepath.Join(l.StateDir, launcherLogFile)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open server log: %w", err)
+	}
+	defer logFile.Close()
+
+	args := append(append([]string{}, l.Args...), "--pid-file", filepath.Join(l.StateDir, launcherPIDFile))
+	cmd := exec.Command(l.Command, args...)
+	cmd.Env = serverEnv(os.Environ())
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", l.Command, err)
+	}
+	slog.Info("Started user-prompt-server", "component", "server_launcher", "command", l.Command, "pid", cmd.Process.Pid, "log_file", logPath)
+
+	// Reap the server when it exits, and notice if that happens on startup.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("%s exited on startup (%v), see %s", l.Command, err, logPath)
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not become healthy within %v, see %s", l.Command, l.StartTimeout, logPath)
+		case <-ticker.C:
+		}
+		if healthy(ctx) {
+			return nil
+		}
+	}
+}
+
+// serverEnv returns the environment for the server without the settings
+// meant for this client, such as its log file, which the server would
+// otherwise pick up too. The config file is kept: it has a section per
+// binary.
+func serverEnv(environ []string) []string {
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, settingsEnvPrefix) && name != configEnv {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// lock creates the lock file, waiting while another client holds it. A lock
+// older than StartTimeout was left behind by a client that died while
+// starting the server, and is taken over.
+func (l *ServerLauncher) lock(ctx context.Context) (unlock func(), err error) {
+	path := filepath.Join(l.StateDir, launcherLockFile)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			fmt.Fprintln(f, strconv.Itoa(os.Getpid()))
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > l.StartTimeout {
+			slog.Warn("Removing stale lock file", "component", "server_launcher", "path", path)
+			os.Remove(path)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for another client to start the server (lock file %s)", path)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// helperServerEnv names the socket TestHelperServer listens on.
+const helperServerEnv = "GUI_TEST_HELPER_SOCKET"
+
+// TestHelperServer is not a test: ServerLauncher runs the test binary with
+// it as a stand-in for user-prompt-server.
+func TestHelperServer(t *testing.T) {
+	socket := os.Getenv(helperServerEnv)
+	if socket == "" {
+		t.Skip("Only runs as a helper process")
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--pid-file" && i+1 < len(args) {
+			os.WriteFile(args[i+1], []byte(strconv.Itoa(os.Getpid())), 0o600)
+		}
+	}
+	starts, _ := os.OpenFile(socket+".starts", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	starts.WriteString("started\n")
+	starts.Close()
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/trigger-prompt", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Input: "started"})
+	})
+	server := &http.Server{Handler: mux}
+	time.AfterFunc(30*time.Second, func() { server.Close() })
+	server.Serve(l)
+}
+
+func TestServerEnv(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "USER_PROMPT_LOG_FILE=/tmp/mcp.log", "USER_PROMPT_NO_UI_POLICY=fail", "USER_PROMPT_CONFIG=/etc/user-prompt.toml", "HOME=/home/user"}
+	expected := []string{"PATH=/usr/bin", "USER_PROMPT_CONFIG=/etc/user-prompt.toml", "HOME=/home/user"}
+	if env := serverEnv(environ); strings.Join(env, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %q, got %q", expected, env)
+	}
+}
+
+func TestServerLauncherStartsServerOnce(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "user-prompt.sock")
+	t.Setenv(helperServerEnv, socket)
+
+	launcher := NewServerLauncher(os.Args[0], []string{"-test.run=^TestHelperServer$", "--"}, filepath.Join(dir, "state"))
+	t.Cleanup(func() {
+		if pid, err := os.ReadFile(filepath.Join(dir, "state", launcherPIDFile)); err == nil {
+			if p, err := strconv.Atoi(string(pid)); err == nil {
+				if process, err := os.FindProcess(p); err == nil {
+					process.Kill()
+				}
+			}
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		rd := NewRemoteDialog("unix://" + socket)
+		rd.Launcher = launcher
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		answer, err := rd.ShowInputDialog(ctx, "Up?", "Test")
+		cancel()
+		if err != nil {
+			t.Fatalf("ShowInputDialog failed: %v", err)
+		}
+		if answer != "started" {
+			t.Errorf("Expected the started server's answer, got %q", answer)
+		}
+	}
+
+	starts, err := os.ReadFile(socket + ".starts")
+	if err != nil {
+		t.Fatalf("Server was never started: %v", err)
+	}
+	if n := strings.Count(string(starts), "started"); n != 1 {
+		t.Errorf("Expected the server to be started once, got %d starts", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state", launcherLockFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+}
+
+func TestServerLauncherTakesOverStaleLock(t *testing.T) {
+	launcher := NewServerLauncher("unused", nil, t.TempDir())
+	launcher.StartTimeout = time.Second
+	lockPath := filepath.Join(launcher.StateDir, launcherLockFile)
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(lockPath, old, old)
+
+	unlock, err := launcher.lock(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package gui
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts the command in a new session, so that it outlives
+// the client and does not receive its terminal's signals.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package gui
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is the DETACHED_PROCESS process creation flag.
+const detachedProcess = 0x00000008
+
+// detachProcess starts the command without a console and in a new process
+// group, so that it outlives the client and does not receive its Ctrl+C.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
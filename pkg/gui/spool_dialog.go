@@ -0,0 +1,229 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+)
+
+const (
+	// SpoolPromptSuffix is the file name suffix of prompt files.
+	SpoolPromptSuffix = ".prompt.json"
+	// SpoolAnswerSuffix is the file name suffix of answer files.
+	SpoolAnswerSuffix = ".answer.json"
+
+	defaultSpoolPollInterval = 200 * time.Millisecond
+)
+
+// SpoolDialog implements DialogProvider through files in a spool directory,
+// which makes it easy to drive from shell scripts, editors and CI harnesses.
+//
+// Each prompt is written as <id>.prompt.json, where <id> is the prompt's ID. The prompt is answered by
+// creating <id>.answer.json containing {"input": "..."} or {"error": "..."}.
+// Both sides must create their files atomically by writing to a temporary
+// name starting with "." and renaming it into place. The provider removes
+// both files once the prompt is answered, cancelled or timed out, and an
+// answer that arrives too late is removed before the next prompt.
+type SpoolDialog struct {
+	Dir string
+	// PollInterval is how often the directory is checked for the answer
+	// when it cannot be watched for changes, e.g. because the system is out
+	// of file watches.
+	PollInterval time.Duration
+}
+
+// NewSpoolDialog creates a new SpoolDialog using the given directory.
+func NewSpoolDialog(dir string) *SpoolDialog {
+	return &SpoolDialog{
+		Dir:          dir,
+		PollInterval: defaultSpoolPollInterval,
+	}
+}
+
+// SpoolPrompt is the content of a prompt file.
+type SpoolPrompt struct {
+	ID        string     `json:"id"`
+	Prompt    string     `json:"prompt"`
+	Title     string     `json:"title"`
+	CreatedAt time.Time  `json:"created_at"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+}
+
+// SpoolAnswer is the content of an answer file.
+type SpoolAnswer struct {
+	Input string `json:"input"`
+	Error string `json:"error,omitempty"`
+}
+
+// ShowInputDialog writes the prompt file and waits for the matching answer file.
+func (sd *SpoolDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	ReportProvider(ctx, ProviderSpool)
+	sd.removeStaleAnswers()
+	spoolPrompt := SpoolPrompt{
+		ID:        spoolPromptID(ctx),
+		Prompt:    prompt,
+		Title:     title,
+		CreatedAt: time.Now().UTC(),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		deadline = deadline.UTC()
+		spoolPrompt.Deadline = &deadline
+	}
+
+	promptPath := filepath.Join(sd.Dir, spoolPrompt.ID+SpoolPromptSuffix)
+	answerPath := filepath.Join(sd.Dir, spoolPrompt.ID+SpoolAnswerSuffix)
+	if err := writeFileAtomic(promptPath, spoolPrompt); err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	defer os.Remove(promptPath)
+	// Also removes an answer written just as the prompt was cancelled
+	defer os.Remove(answerPath)
+	log := slog.With("component", "spool_dialog", "prompt_file", promptPath, "answer_file", answerPath)
+	log.Info("Wrote prompt file, waiting for the answer")
+
+	// The directory is watched before the first check, so that an answer
+	// written in between is not missed.
+	var changes <-chan fsnotify.Event
+	var watchErrors <-chan error
+	var poll <-chan time.Time
+	watcher, err := watchDir(sd.Dir)
+	if err == nil {
+		defer watcher.Close()
+		changes, watchErrors = watcher.Events, watcher.Errors
+	} else {
+		log.Warn("Cannot watch the spool directory, polling it instead", "error", err)
+		poll = time.Tick(sd.pollInterval())
+	}
+
+	for {
+		answer, err := readSpoolAnswer(answerPath)
+		if err == nil {
+			if answer.Error != "" {
+				return "", fmt.Errorf("prompt answered with error: %s", answer.Error)
+			}
+			return answer.Input, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to read answer file: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for answer file was cancelled: %w", ctx.Err())
+		case <-changes: // Any change in the directory may be the answer
+		case <-poll:
+		case err := <-watchErrors:
+			// Changes may have been lost, e.g. because the event queue overflowed
+			log.Warn("Watching the spool directory failed, polling it instead", "error", err)
+			changes, watchErrors = nil, nil
+			poll = time.Tick(sd.pollInterval())
+		}
+	}
+}
+
+// watchDir watches dir for created and changed files.
+func watchDir(dir string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+func (sd *SpoolDialog) pollInterval() time.Duration {
+	if sd.PollInterval <= 0 {
+		return defaultSpoolPollInterval
+	}
+	return sd.PollInterval
+}
+
+// spoolPromptID returns the caller's prompt ID, so that the files match the
+// prompt's history record, or a new ID if it cannot be used as a file name.
+func spoolPromptID(ctx context.Context) string {
+	id := PromptID(ctx)
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\`) {
+		return uuid.NewString()
+	}
+	return id
+}
+
+// removeStaleAnswers removes answer files whose prompt file is gone, i.e.
+// answers to prompts that were cancelled or timed out in the meantime.
+func (sd *SpoolDialog) removeStaleAnswers() {
+	answers, _ := filepath.Glob(filepath.Join(sd.Dir, "*"+SpoolAnswerSuffix))
+	for _, answerPath := range answers {
+		id := strings.TrimSuffix(filepath.Base(answerPath), SpoolAnswerSuffix)
+		if _, err := os.Stat(filepath.Join(sd.Dir, id+SpoolPromptSuffix)); errors.Is(err, os.ErrNotExist) {
+			slog.Info("Removing answer to a prompt that is no longer waiting", "component", "spool_dialog", "answer_file", answerPath)
+			os.Remove(answerPath)
+		}
+	}
+}
+
+// SupportsConcurrentPrompts implements ConcurrentDialogProvider.
+func (sd *SpoolDialog) SupportsConcurrentPrompts() bool {
+	return true // Every prompt has its own files
+}
+
+// CheckDependencies ensures the spool directory exists and is writable.
+func (sd *SpoolDialog) CheckDependencies() error {
+	if sd.Dir == "" {
+		return errors.New("no spool directory configured")
+	}
+	if err := os.MkdirAll(sd.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	probe, err := os.CreateTemp(sd.Dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("spool directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+func readSpoolAnswer(path string) (SpoolAnswer, error) {
+	var answer SpoolAnswer
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return answer, err
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return answer, fmt.Errorf("invalid answer JSON in %s: %w", path, err)
+	}
+	return answer, nil
+}
+
+// writeFileAtomic writes v as JSON to a hidden temporary file next to path
+// and renames it into place, so readers never observe a partial file.
+func writeFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
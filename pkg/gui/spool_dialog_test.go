@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// answerSpool waits for a prompt file in dir, answers it atomically and
+// returns the prompt's ID.
+func answerSpool(t *testing.T, dir string, answer SpoolAnswer) string {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+SpoolPromptSuffix))
+		if len(matches) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(matches[0])
+		if err != nil {
+			t.Errorf("Failed to read prompt file: %v", err)
+			return ""
+		}
+		var prompt SpoolPrompt
+		if err := json.Unmarshal(data, &prompt); err != nil {
+			t.Errorf("Invalid prompt JSON: %v", err)
+			return ""
+		}
+		if prompt.Prompt != "Continue?" || prompt.Title != "Question" {
+			t.Errorf("Unexpected prompt file content: %+v", prompt)
+		}
+		if err := writeFileAtomic(filepath.Join(dir, prompt.ID+SpoolAnswerSuffix), answer); err != nil {
+			t.Errorf("Failed to write answer file: %v", err)
+		}
+		return prompt.ID
+	}
+	t.Error("Prompt file was never written")
+	return ""
+}
+
+func TestSpoolDialog_ShowInputDialog(t *testing.T) {
+	dir := t.TempDir()
+	dialog := NewSpoolDialog(dir)
+	dialog.PollInterval = 10 * time.Millisecond
+
+	go answerSpool(t, dir, SpoolAnswer{Input: "yes"})
+
+	result, err := dialog.ShowInputDialog(context.Background(), "Continue?", "Question")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result != "yes" {
+		t.Errorf("Expected result 'yes', got: %q", result)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected spool directory to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestSpoolDialog_WatchesForAnswer(t *testing.T) {
+	dir := t.TempDir()
+	dialog := NewSpoolDialog(dir)
+	dialog.PollInterval = time.Hour // Only a change in the directory can wake it up
+
+	go answerSpool(t, dir, SpoolAnswer{Input: "yes"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if result, err := dialog.ShowInputDialog(ctx, "Continue?", "Question"); err != nil || result != "yes" {
+		t.Errorf("Expected result 'yes', got %q and error %v", result, err)
+	}
+}
+
+func TestSpoolDialog_AnswerError(t *testing.T) {
+	dir := t.TempDir()
+	dialog := NewSpoolDialog(dir)
+	dialog.PollInterval = 10 * time.Millisecond
+
+	go answerSpool(t, dir, SpoolAnswer{Error: "rejected"})
+
+	_, err := dialog.ShowInputDialog(context.Background(), "Continue?", "Question")
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Expected error from answer file, got: %v", err)
+	}
+}
+
+func TestSpoolDialog_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	dialog := NewSpoolDialog(dir)
+	dialog.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := dialog.ShowInputDialog(ctx, "Continue?", "Question"); err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected prompt file to be removed after timeout, found %d entries", len(entries))
+	}
+}
+
+func TestSpoolDialog_RemovesLateAnswers(t *testing.T) {
+	dir := t.TempDir()
+	dialog := NewSpoolDialog(dir)
+	dialog.PollInterval = 10 * time.Millisecond
+
+	// An answer to a prompt that timed out, and one to a prompt that is
+	// still waiting in another client
+	late := filepath.Join(dir, "late"+SpoolAnswerSuffix)
+	pending := filepath.Join(dir, "pending"+SpoolAnswerSuffix)
+	for _, path := range []string{late, pending} {
+		if err := writeFileAtomic(path, SpoolAnswer{Input: "too late"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "pending"+SpoolPromptSuffix), SpoolPrompt{ID: "pending"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	dialog.ShowInputDialog(ctx, "Continue?", "Question")
+
+	if _, err := os.Stat(late); !os.IsNotExist(err) {
+		t.Errorf("Expected the late answer to be removed, got %v", err)
+	}
+	if _, err := os.Stat(pending); err != nil {
+		t.Errorf("Expected the answer to the waiting prompt to be kept, got %v", err)
+	}
+}
+
+func TestSpoolDialog_UsesPromptID(t *testing.T) {
+	tests := []struct {
+		id     string
+		usesID bool
+	}{
+		{"caller-id", true},
+		{"", false},
+		{"../escape", false},
+		{".hidden", false},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		ids := make(chan string, 1)
+		go func() { ids <- answerSpool(t, dir, SpoolAnswer{Input: "yes"}) }()
+
+		ctx, cancel := context.WithTimeout(WithPromptID(context.Background(), tt.id), 5*time.Second)
+		_, err := NewSpoolDialog(dir).ShowInputDialog(ctx, "Continue?", "Question")
+		cancel()
+		if err != nil {
+			t.Fatalf("%q: ShowInputDialog failed: %v", tt.id, err)
+		}
+		if id := <-ids; id == "" || (id == tt.id) != tt.usesID {
+			t.Errorf("%q: expected the files to be named after the prompt ID: %t, got %q", tt.id, tt.usesID, id)
+		}
+	}
+}
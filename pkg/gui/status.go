@@ -0,0 +1,85 @@
+package gui
+
+import (
+	"context"
+)
+
+// StatusFunc receives human-readable status updates from a dialog provider
+// while it waits for the user, such as "user is typing".
+type StatusFunc func(status string)
+
+type statusKey struct{}
+
+// WithStatusReporter returns a context that delivers status updates reported
+// by dialog providers to fn. fn must not block.
+func WithStatusReporter(ctx context.Context, fn StatusFunc) context.Context {
+	return context.WithValue(ctx, statusKey{}, fn)
+}
+
+// ReportStatus sends a status update to the reporter attached to ctx, if any.
+func ReportStatus(ctx context.Context, status string) {
+	if fn, ok := ctx.Value(statusKey{}).(StatusFunc); ok && fn != nil {
+		fn(status)
+	}
+}
+
+// ProviderFunc receives the name of the dialog provider that shows a prompt.
+type ProviderFunc func(provider string)
+
+type providerKey struct{}
+
+// WithProviderReporter returns a context that tells fn which provider shows
+// the prompt. Providers that delegate to others, such as FallbackDialog, do
+// not report themselves, so the last name reported is the provider that
+// answered. fn must not block.
+func WithProviderReporter(ctx context.Context, fn ProviderFunc) context.Context {
+	return context.WithValue(ctx, providerKey{}, fn)
+}
+
+// ReportProvider tells the reporter attached to ctx, if any, that the named
+// provider shows the prompt.
+func ReportProvider(ctx context.Context, provider string) {
+	if fn, ok := ctx.Value(providerKey{}).(ProviderFunc); ok && fn != nil {
+		fn(provider)
+	}
+}
+
+type promptIDKey struct{}
+
+// WithPromptID returns a context carrying the ID of the prompt being shown,
+// so that providers handing the prompt to another process use the same ID
+// as the caller.
+func WithPromptID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, promptIDKey{}, id)
+}
+
+// PromptID returns the prompt ID attached to ctx, or "" if there is none.
+func PromptID(ctx context.Context) string {
+	id, _ := ctx.Value(promptIDKey{}).(string)
+	return id
+}
+
+type queueLengthKey struct{}
+
+// WithQueueLength returns a context carrying fn, which returns the number of
+// prompts waiting behind the one being shown, so that providers can pass it
+// on to a server.
+func WithQueueLength(ctx context.Context, fn func() int) context.Context {
+	return context.WithValue(ctx, queueLengthKey{}, fn)
+}
+
+// QueueLength returns the number of prompts waiting behind the one being
+// shown, or 0 if ctx does not carry a queue length.
+func QueueLength(ctx context.Context) int {
+	if fn, ok := ctx.Value(queueLengthKey{}).(func() int); ok && fn != nil {
+		return fn()
+	}
+	return 0
+}
+
+// hasStatusReporter reports whether a status reporter is attached to ctx, so
+// providers can skip work whose only purpose is reporting status.
+func hasStatusReporter(ctx context.Context) bool {
+	fn, ok := ctx.Value(statusKey{}).(StatusFunc)
+	return ok && fn != nil
+}
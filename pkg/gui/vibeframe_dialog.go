@@ -3,7 +3,6 @@ package gui
 import (
 	"context"
 	"errors"
-	"log"
 )
 
 // VibeframeDialog implements DialogProvider for Vibeframe integration.
@@ -23,8 +22,7 @@ type VibeframeDialog struct {
 func NewVibeframeDialog(promptRequester func(ctx context.Context, prompt, title string) (string, error)) *VibeframeDialog {
 	if promptRequester == nil {
 		// This should not happen if initialized correctly from main.go
-		log.Fatal("VibeframeDialog: promptRequester function cannot be nil")
-		return nil // Or handle error appropriately
+		panic("VibeframeDialog: promptRequester function cannot be nil")
 	}
 	return &VibeframeDialog{
 		requestPromptFunc: promptRequester,
@@ -34,7 +32,6 @@ func NewVibeframeDialog(promptRequester func(ctx context.Context, prompt, title
 // ShowInputDialog for VibeframeDialog uses the provided requestPromptFunc.
 func (v *VibeframeDialog) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
 	if v.requestPromptFunc == nil {
-		log.Println("Error: VibeframeDialog.requestPromptFunc is not set.")
 		return "", errors.New("VibeframeDialog not properly initialized")
 	}
 	// Pass the context, prompt, and title to the actual prompting logic
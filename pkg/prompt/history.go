@@ -0,0 +1,132 @@
+package prompt
+
+import (
+	"sync"
+	"time"
+)
+
+// States of a prompt in the history.
+const (
+	StateQueued   = "queued"   // Waiting for other prompts to be answered
+	StateShowing  = "showing"  // Shown to the user
+	StateAnswered = "answered" // The user answered
+	StateFailed   = "failed"   // Timed out, cancelled or failed otherwise
+)
+
+// defaultHistoryLength is how many finished prompts are kept by default.
+const defaultHistoryLength = 100
+
+// Record describes a prompt and, once it has finished, its outcome.
+type Record struct {
+	ID          string     `json:"id"`
+	Prompt      string     `json:"prompt"`
+	Title       string     `json:"title"`
+	Priority    int        `json:"priority"`
+	State       string     `json:"state"`
+	Answer      string     `json:"answer,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ShownAt     *time.Time `json:"shown_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// promptHistory keeps the pending prompts and the most recent finished
+// ones. The zero value keeps an unlimited number of finished prompts.
+type promptHistory struct {
+	mu       sync.Mutex
+	maxLen   int
+	pending  []*Record // In request order
+	finished []Record  // Oldest first
+}
+
+func (h *promptHistory) add(opts PromptOptions) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = append(h.pending, &Record{
+		ID:          opts.ID,
+		Prompt:      opts.Prompt,
+		Title:       opts.Title,
+		Priority:    opts.Priority,
+		State:       StateQueued,
+		RequestedAt: time.Now(),
+	})
+}
+
+func (h *promptHistory) shown(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r := h.pendingLocked(id); r != nil {
+		now := time.Now()
+		r.State, r.ShownAt = StateShowing, &now
+	}
+}
+
+// finish moves the prompt from the pending prompts to the history.
+func (h *promptHistory) finish(id, answer string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, r := range h.pending {
+		if r.ID != id {
+			continue
+		}
+		h.pending = append(h.pending[:i], h.pending[i+1:]...)
+		now := time.Now()
+		r.FinishedAt = &now
+		if err != nil {
+			r.State, r.Error = StateFailed, err.Error()
+		} else {
+			r.State, r.Answer = StateAnswered, answer
+		}
+		h.finished = append(h.finished, *r)
+		if h.maxLen > 0 && len(h.finished) > h.maxLen {
+			h.finished = append([]Record(nil), h.finished[len(h.finished)-h.maxLen:]...)
+		}
+		return
+	}
+}
+
+func (h *promptHistory) pendingLocked(id string) *Record {
+	for _, r := range h.pending {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Pending returns the prompts that are queued or shown, in request order.
+func (s *Service) Pending() []Record {
+	s.history.mu.Lock()
+	defer s.history.mu.Unlock()
+	records := make([]Record, 0, len(s.history.pending))
+	for _, r := range s.history.pending {
+		records = append(records, *r)
+	}
+	return records
+}
+
+// History returns the finished prompts, most recent first.
+func (s *Service) History() []Record {
+	s.history.mu.Lock()
+	defer s.history.mu.Unlock()
+	records := make([]Record, 0, len(s.history.finished))
+	for i := len(s.history.finished) - 1; i >= 0; i-- {
+		records = append(records, s.history.finished[i])
+	}
+	return records
+}
+
+// Lookup returns the pending or finished prompt with the given ID.
+func (s *Service) Lookup(id string) (Record, bool) {
+	s.history.mu.Lock()
+	defer s.history.mu.Unlock()
+	if r := s.history.pendingLocked(id); r != nil {
+		return *r, true
+	}
+	for _, r := range s.history.finished {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
@@ -0,0 +1,144 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrQueueFull is returned when a prompt arrives while the maximum number
+// of prompts are already waiting.
+var ErrQueueFull = errors.New("prompt queue is full")
+
+// queuedPrompt is a prompt waiting for a free dialog slot.
+type queuedPrompt struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	onUpdate func(ahead int)
+}
+
+// promptQueue admits prompts to the dialog provider, at most `slots` at a
+// time. Waiting prompts are served by descending priority, then in arrival
+// order.
+type promptQueue struct {
+	mu      sync.Mutex
+	slots   int
+	maxLen  int
+	running int
+	seq     uint64
+	waiting []*queuedPrompt
+}
+
+func newPromptQueue(slots, maxLen int) *promptQueue {
+	if slots < 1 {
+		slots = 1
+	}
+	return &promptQueue{slots: slots, maxLen: maxLen}
+}
+
+// queueUpdate is a pending onUpdate call. Callbacks are invoked after the
+// queue lock is released so they may safely call back into the queue.
+type queueUpdate struct {
+	fn    func(ahead int)
+	ahead int
+}
+
+func runUpdates(updates []queueUpdate) {
+	for _, u := range updates {
+		u.fn(u.ahead)
+	}
+}
+
+// acquire blocks until the prompt may be shown or ctx is done. onUpdate, if
+// not nil, is called with the number of prompts ahead whenever the queue
+// changes while the prompt waits, and with 0 once the prompt is admitted.
+func (q *promptQueue) acquire(ctx context.Context, priority int, onUpdate func(ahead int)) error {
+	q.mu.Lock()
+	if q.running < q.slots && len(q.waiting) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return nil
+	}
+	if q.maxLen > 0 && len(q.waiting) >= q.maxLen {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	q.seq++
+	p := &queuedPrompt{priority: priority, seq: q.seq, ready: make(chan struct{}), onUpdate: onUpdate}
+	q.waiting = append(q.waiting, p)
+	sort.SliceStable(q.waiting, func(i, j int) bool {
+		return q.waiting[i].priority > q.waiting[j].priority
+	})
+	updates := q.positionsLocked()
+	q.mu.Unlock()
+	runUpdates(updates)
+
+	select {
+	case <-p.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		select {
+		case <-p.ready:
+			// Admitted concurrently with cancellation; hand the slot on.
+			q.mu.Unlock()
+			q.release()
+		default:
+			q.removeLocked(p)
+			updates := q.positionsLocked()
+			q.mu.Unlock()
+			runUpdates(updates)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot and admits the next waiting prompts.
+func (q *promptQueue) release() {
+	q.mu.Lock()
+	q.running--
+	var updates []queueUpdate
+	for q.running < q.slots && len(q.waiting) > 0 {
+		next := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.running++
+		close(next.ready)
+		if next.onUpdate != nil {
+			updates = append(updates, queueUpdate{next.onUpdate, 0})
+		}
+	}
+	updates = append(updates, q.positionsLocked()...)
+	q.mu.Unlock()
+	runUpdates(updates)
+}
+
+// length returns the number of waiting prompts.
+func (q *promptQueue) length() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+func (q *promptQueue) removeLocked(p *queuedPrompt) {
+	for i, w := range q.waiting {
+		if w == p {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// positionsLocked reports to every waiting prompt how many prompts are
+// ahead of it: the ones being shown plus the ones queued before it.
+func (q *promptQueue) positionsLocked() []queueUpdate {
+	var updates []queueUpdate
+	for i, w := range q.waiting {
+		if w.onUpdate != nil {
+			updates = append(updates, queueUpdate{w.onUpdate, q.running + i})
+		}
+	}
+	return updates
+}
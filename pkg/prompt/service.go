@@ -2,21 +2,36 @@ package prompt
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/nazar256/user-prompt-mcp/internal/logging"
 	"github.com/nazar256/user-prompt-mcp/pkg/gui"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const defaultPromptServerURL = "http://localhost:3030"
 
+// tracerName names the tracer, which is looked up from the global provider
+// at span start so that a provider installed later is used.
+const tracerName = "github.com/nazar256/user-prompt-mcp/pkg/prompt"
+
+// ErrTimeout is returned when the user does not answer a prompt in time.
+var ErrTimeout = errors.New("prompt timed out")
+
 // Service handles user input prompts
 type Service struct {
 	dialog     gui.DialogProvider
 	timeout    time.Duration
 	defaultMsg string
-	mutex      sync.Mutex
+	queue      *promptQueue
+	history    promptHistory
 }
 
 // ServiceOptions contains options for creating a new PromptService
@@ -24,14 +39,28 @@ type ServiceOptions struct {
 	Dialog     gui.DialogProvider
 	Timeout    time.Duration
 	DefaultMsg string
+	// MaxQueueLength limits how many prompts may wait while others are
+	// shown; further prompts fail with ErrQueueFull. Zero means unlimited.
+	MaxQueueLength int
+	// MaxConcurrent is how many prompts may be shown at the same time. It
+	// only takes effect if the dialog provider supports concurrent prompts
+	// (see gui.ConcurrentDialogProvider); otherwise prompts are shown one
+	// at a time.
+	MaxConcurrent int
+	// HistoryLength is how many finished prompts are kept for Pending,
+	// History and Lookup.
+	HistoryLength int
 }
 
 // DefaultOptions returns the default options for the prompt service
 func DefaultOptions() ServiceOptions {
 	return ServiceOptions{
-		Dialog:     gui.NewRemoteDialog(defaultPromptServerURL),
-		Timeout:    time.Minute * 20, // 20 minute default timeout
-		DefaultMsg: "Cursor is requesting additional input",
+		Dialog:         gui.NewRemoteDialog(defaultPromptServerURL),
+		Timeout:        time.Minute * 20, // 20 minute default timeout
+		DefaultMsg:     "Cursor is requesting additional input",
+		MaxQueueLength: 10,
+		MaxConcurrent:  1,
+		HistoryLength:  defaultHistoryLength,
 	}
 }
 
@@ -46,28 +75,45 @@ func NewService(opts ServiceOptions) *Service {
 	if opts.DefaultMsg == "" {
 		opts.DefaultMsg = DefaultOptions().DefaultMsg
 	}
+	if opts.HistoryLength == 0 {
+		opts.HistoryLength = DefaultOptions().HistoryLength
+	}
+
+	slots := 1
+	if opts.MaxConcurrent > 1 && gui.SupportsConcurrentPrompts(opts.Dialog) {
+		slots = opts.MaxConcurrent
+	}
 
 	return &Service{
 		dialog:     opts.Dialog,
 		timeout:    opts.Timeout,
 		defaultMsg: opts.DefaultMsg,
+		queue:      newPromptQueue(slots, opts.MaxQueueLength),
+		history:    promptHistory{maxLen: opts.HistoryLength},
 	}
 }
 
 // PromptOptions contains options for a specific prompt
 type PromptOptions struct {
+	// ID identifies the prompt in the history; a random one is used if empty.
+	ID         string
 	Prompt     string
 	Title      string
 	Timeout    time.Duration
 	DefaultMsg string
+	// Priority orders waiting prompts; higher priorities are shown first.
+	Priority int
+	// OnQueueUpdate, if set, is called while the prompt waits behind
+	// others with the number of prompts ahead of it, and with 0 once it is
+	// shown. It must not block.
+	OnQueueUpdate func(ahead int)
 }
 
 // PromptForInput displays a prompt to the user and returns their input
 // The prompt is displayed with the specified options and will timeout after the specified duration
+// If other prompts are being shown, it waits in the queue first; the timeout
+// only starts once the prompt is shown, while waiting is bounded by ctx.
 func (s *Service) PromptForInput(ctx context.Context, opts PromptOptions) (string, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
 	// Use default values if not provided
 	if opts.Title == "" {
 		opts.Title = "User Input Required"
@@ -78,20 +124,57 @@ func (s *Service) PromptForInput(ctx context.Context, opts PromptOptions) (strin
 	if opts.Timeout == 0 {
 		opts.Timeout = s.timeout
 	}
+	if opts.ID == "" {
+		opts.ID = uuid.NewString()
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "Service.PromptForInput", trace.WithAttributes(
+		attribute.Int("prompt.priority", opts.Priority),
+		attribute.String("prompt.timeout", opts.Timeout.String())))
+	defer span.End()
+	s.history.add(opts)
+	result, err := s.promptForInput(ctx, span, opts)
+	s.history.finish(opts.ID, result, err)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+// promptForInput queues and shows the prompt, recording the wait in span.
+func (s *Service) promptForInput(ctx context.Context, span trace.Span, opts PromptOptions) (string, error) {
+	onUpdate := opts.OnQueueUpdate
+	if onUpdate == nil {
+		onUpdate = func(ahead int) {
+			if ahead > 0 {
+				slog.Info("Prompt is queued", logging.Title(opts.Title), "ahead", ahead)
+			}
+		}
+	}
+	if err := s.queue.acquire(ctx, opts.Priority, onUpdate); err != nil {
+		if err == ErrQueueFull {
+			return "", err
+		}
+		return "", fmt.Errorf("prompt cancelled while queued: %w", err)
+	}
+	defer s.queue.release()
+	span.AddEvent("prompt.dequeued")
+	s.history.shown(opts.ID)
 
 	// Create a timeout context based on the provided context and the prompt timeout
 	timeoutCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
+	dialogCtx := gui.WithQueueLength(gui.WithPromptID(timeoutCtx, opts.ID), s.QueueLength)
 
-	// Channel to receive result
+	// Channel to receive result, buffered so the dialog goroutine can exit after a timeout
 	resultCh := make(chan struct {
 		result string
 		err    error
-	})
+	}, 1)
 
 	// Run the dialog in a goroutine
 	go func() {
-		result, err := s.dialog.ShowInputDialog(timeoutCtx, opts.Prompt, opts.Title)
+		result, err := s.dialog.ShowInputDialog(dialogCtx, opts.Prompt, opts.Title)
 		resultCh <- struct {
 			result string
 			err    error
@@ -110,6 +193,16 @@ func (s *Service) PromptForInput(ctx context.Context, opts PromptOptions) (strin
 		if ctx.Err() != nil {
 			return "", fmt.Errorf("prompt cancelled: %w", ctx.Err())
 		}
-		return "", fmt.Errorf("prompt timed out after %v", opts.Timeout)
+		return "", fmt.Errorf("%w after %v", ErrTimeout, opts.Timeout)
 	}
 }
+
+// QueueLength returns the number of prompts waiting to be shown.
+func (s *Service) QueueLength() int {
+	return s.queue.length()
+}
+
+// Timeout returns the default time a prompt is shown before it times out.
+func (s *Service) Timeout() time.Duration {
+	return s.timeout
+}
@@ -86,3 +86,180 @@ func TestPromptForInput(t *testing.T) {
 		t.Error("Expected timeout error, got nil")
 	}
 }
+
+// gatedDialogProvider blocks every prompt until a value is sent on answers
+// and records the order in which prompts were shown.
+type gatedDialogProvider struct {
+	answers    chan string
+	shown      chan string
+	concurrent bool
+}
+
+func newGatedDialogProvider(concurrent bool) *gatedDialogProvider {
+	return &gatedDialogProvider{answers: make(chan string), shown: make(chan string, 10), concurrent: concurrent}
+}
+
+func (g *gatedDialogProvider) ShowInputDialog(ctx context.Context, prompt string, title string) (string, error) {
+	g.shown <- prompt
+	select {
+	case answer := <-g.answers:
+		return answer, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (g *gatedDialogProvider) CheckDependencies() error {
+	return nil
+}
+
+func (g *gatedDialogProvider) SupportsConcurrentPrompts() bool {
+	return g.concurrent
+}
+
+func waitShown(t *testing.T, g *gatedDialogProvider) string {
+	t.Helper()
+	select {
+	case prompt := <-g.shown:
+		return prompt
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a prompt to be shown")
+		return ""
+	}
+}
+
+func TestPromptForInput_QueuesByPriority(t *testing.T) {
+	dialog := newGatedDialogProvider(false)
+	service := NewService(ServiceOptions{Dialog: dialog})
+
+	go service.PromptForInput(context.Background(), PromptOptions{Prompt: "first"})
+	waitShown(t, dialog)
+
+	queued := make(chan int, 10)
+	go service.PromptForInput(context.Background(), PromptOptions{Prompt: "low", Priority: 0})
+	for service.QueueLength() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go service.PromptForInput(context.Background(), PromptOptions{
+		Prompt:        "high",
+		Priority:      10,
+		OnQueueUpdate: func(ahead int) { queued <- ahead },
+	})
+
+	if ahead := <-queued; ahead != 1 {
+		t.Errorf("Expected high priority prompt to be queued behind 1 prompt, got %d", ahead)
+	}
+
+	dialog.answers <- "done"
+	if prompt := waitShown(t, dialog); prompt != "high" {
+		t.Errorf("Expected high priority prompt to be shown next, got %q", prompt)
+	}
+	if ahead := <-queued; ahead != 0 {
+		t.Errorf("Expected 0 prompts ahead once shown, got %d", ahead)
+	}
+	dialog.answers <- "done"
+	if prompt := waitShown(t, dialog); prompt != "low" {
+		t.Errorf("Expected low priority prompt to be shown last, got %q", prompt)
+	}
+	dialog.answers <- "done"
+}
+
+func TestPromptForInput_QueueFull(t *testing.T) {
+	dialog := newGatedDialogProvider(false)
+	service := NewService(ServiceOptions{Dialog: dialog, MaxQueueLength: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go service.PromptForInput(ctx, PromptOptions{Prompt: "shown"})
+	waitShown(t, dialog)
+	go service.PromptForInput(ctx, PromptOptions{Prompt: "queued"})
+	for service.QueueLength() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := service.PromptForInput(ctx, PromptOptions{Prompt: "rejected"}); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull, got: %v", err)
+	}
+}
+
+func TestPromptForInput_QueuedTimeoutStartsWhenShown(t *testing.T) {
+	dialog := newGatedDialogProvider(false)
+	service := NewService(ServiceOptions{Dialog: dialog, Timeout: 100 * time.Millisecond})
+
+	go service.PromptForInput(context.Background(), PromptOptions{Prompt: "first", Timeout: time.Second})
+	waitShown(t, dialog)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := service.PromptForInput(context.Background(), PromptOptions{Prompt: "second"})
+		result <- err
+	}()
+
+	// Wait longer than the timeout before the first prompt is answered
+	time.Sleep(150 * time.Millisecond)
+	dialog.answers <- "first answer"
+	waitShown(t, dialog)
+	dialog.answers <- "second answer"
+
+	if err := <-result; err != nil {
+		t.Errorf("Expected queued prompt to get its full timeout once shown, got: %v", err)
+	}
+}
+
+func TestPromptForInput_Concurrent(t *testing.T) {
+	dialog := newGatedDialogProvider(true)
+	service := NewService(ServiceOptions{Dialog: dialog, MaxConcurrent: 2})
+
+	go service.PromptForInput(context.Background(), PromptOptions{Prompt: "one"})
+	go service.PromptForInput(context.Background(), PromptOptions{Prompt: "two"})
+
+	// Both prompts are shown without either being answered
+	waitShown(t, dialog)
+	waitShown(t, dialog)
+	if service.QueueLength() != 0 {
+		t.Errorf("Expected no queued prompts, got %d", service.QueueLength())
+	}
+	dialog.answers <- "done"
+	dialog.answers <- "done"
+}
+
+func TestHistory(t *testing.T) {
+	dialog := newGatedDialogProvider(false)
+	service := NewService(ServiceOptions{Dialog: dialog, HistoryLength: 2})
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		service.PromptForInput(ctx, PromptOptions{ID: "first", Prompt: "Deploy?"})
+	}()
+	waitShown(t, dialog)
+	if pending := service.Pending(); len(pending) != 1 || pending[0].ID != "first" || pending[0].State != StateShowing {
+		t.Fatalf("Expected the first prompt to be showing, got %+v", pending)
+	}
+	dialog.answers <- "yes"
+	<-done
+
+	for _, id := range []string{"second", "third"} {
+		go func() { dialog.answers <- "no" }()
+		if _, err := service.PromptForInput(ctx, PromptOptions{ID: id, Prompt: "Again?"}); err != nil {
+			t.Fatalf("PromptForInput failed: %v", err)
+		}
+		<-dialog.shown
+	}
+
+	if pending := service.Pending(); len(pending) != 0 {
+		t.Errorf("Expected no pending prompts, got %d", len(pending))
+	}
+	history := service.History()
+	if len(history) != 2 || history[0].ID != "third" || history[1].ID != "second" {
+		t.Fatalf("Expected the two most recent prompts, newest first, got %+v", history)
+	}
+	if _, ok := service.Lookup("first"); ok {
+		t.Error("Expected the oldest prompt to have dropped out of the history")
+	}
+	record, ok := service.Lookup("third")
+	if !ok || record.State != StateAnswered || record.Answer != "no" || record.ShownAt == nil || record.FinishedAt == nil {
+		t.Errorf("Expected the answered prompt with its timing, got %+v", record)
+	}
+}
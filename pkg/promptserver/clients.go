@@ -0,0 +1,121 @@
+package promptserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UI client transports.
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+)
+
+// uiClient is a connected SSE or WebSocket client.
+type uiClient struct {
+	ClientInfo
+	events chan uiEvent
+	lagged chan struct{} // Closed when the client fell behind and has to reconnect
+}
+
+// ClientInfo describes a connected UI in the /api/clients response.
+type ClientInfo struct {
+	ID           string    `json:"id"`
+	Transport    string    `json:"transport"`
+	RemoteAddr   string    `json:"remote_addr"`
+	ForwardedFor string    `json:"forwarded_for,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	ConnectedAt  time.Time `json:"connected_at"`
+}
+
+func newUIClient(r *http.Request, transport string) *uiClient {
+	return &uiClient{
+		ClientInfo: ClientInfo{
+			ID:           uuid.NewString(),
+			Transport:    transport,
+			RemoteAddr:   r.RemoteAddr,
+			ForwardedFor: r.Header.Get("X-Forwarded-For"),
+			UserAgent:    r.UserAgent(),
+			ConnectedAt:  time.Now().UTC(),
+		},
+		events: make(chan uiEvent, eventClientBufferSize),
+		lagged: make(chan struct{}),
+	}
+}
+
+// clientRegistry is the set of connected UI clients. Clients are only ever
+// removed under its lock, so a client's lagged channel is closed at most once
+// and its events channel is never closed.
+type clientRegistry struct {
+	mu      sync.Mutex
+	clients map[string]*uiClient
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{clients: make(map[string]*uiClient)}
+}
+
+func (cr *clientRegistry) register(client *uiClient) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.clients[client.ID] = client
+}
+
+// unregister removes client, unless it has already been dropped as lagging.
+func (cr *clientRegistry) unregister(client *uiClient) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.clients[client.ID] == client {
+		delete(cr.clients, client.ID)
+	}
+}
+
+// broadcast queues event for every client, and drops the clients whose
+// queue is full.
+func (cr *clientRegistry) broadcast(event uiEvent, onLagged func(client *uiClient)) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for id, client := range cr.clients {
+		select {
+		case client.events <- event:
+		default:
+			delete(cr.clients, id)
+			close(client.lagged)
+			onLagged(client)
+		}
+	}
+}
+
+func (cr *clientRegistry) count() int {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	return len(cr.clients)
+}
+
+// list returns the connected clients, longest-connected first.
+func (cr *clientRegistry) list() []ClientInfo {
+	cr.mu.Lock()
+	infos := make([]ClientInfo, 0, len(cr.clients))
+	for _, client := range cr.clients {
+		infos = append(infos, client.ClientInfo)
+	}
+	cr.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+	return infos
+}
+
+// clientsHandler serves GET /api/clients, the list of connected UIs.
+func (s *Server) clientsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Clients []ClientInfo `json:"clients"`
+	}{s.clients.list()})
+}
@@ -0,0 +1,88 @@
+package promptserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// waitClients waits until /api/clients lists n clients and returns them.
+func waitClients(t *testing.T, ts *httptest.Server, n int) []ClientInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	var list struct {
+		Clients []ClientInfo `json:"clients"`
+	}
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(ts.URL + "/api/clients")
+		if err != nil {
+			t.Fatalf("Clients request failed: %v", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode the clients: %v", err)
+		}
+		if len(list.Clients) == n {
+			return list.Clients
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d clients, got %+v", n, list.Clients)
+	return nil
+}
+
+func TestClientsList(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	before := time.Now().UTC()
+
+	sseCtx, disconnectSSE := context.WithCancel(context.Background())
+	defer disconnectSSE()
+	req, _ := http.NewRequestWithContext(sseCtx, http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("User-Agent", "sse-browser")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect to /events: %v", err)
+	}
+	defer resp.Body.Close()
+	waitClients(t, ts, 1)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", http.Header{"User-Agent": {"ws-browser"}})
+	if err != nil {
+		t.Fatalf("Failed to connect to /ws: %v", err)
+	}
+	defer conn.Close()
+
+	clients := waitClients(t, ts, 2)
+	after := time.Now().UTC()
+	expected := []ClientInfo{
+		{Transport: transportSSE, UserAgent: "sse-browser", ForwardedFor: "203.0.113.7"},
+		{Transport: transportWebSocket, UserAgent: "ws-browser"},
+	}
+	for i, client := range clients {
+		want := expected[i]
+		if client.Transport != want.Transport || client.UserAgent != want.UserAgent || client.ForwardedFor != want.ForwardedFor {
+			t.Errorf("Expected client %d to be %+v, got %+v", i, want, client)
+		}
+		if client.ID == "" || client.RemoteAddr == "" {
+			t.Errorf("Expected client %d to have an ID and a remote address, got %+v", i, client)
+		}
+		if client.ConnectedAt.Before(before) || client.ConnectedAt.After(after) {
+			t.Errorf("Expected client %d to connect between %v and %v, got %v", i, before, after, client.ConnectedAt)
+		}
+	}
+
+	conn.Close()
+	if remaining := waitClients(t, ts, 1); remaining[0].Transport != transportSSE {
+		t.Errorf("Expected the SSE client to remain, got %+v", remaining[0])
+	}
+	disconnectSSE()
+	waitClients(t, ts, 0)
+}
@@ -0,0 +1,189 @@
+package promptserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Delivery settings for UI events.
+const (
+	eventReplaySize       = 256              // Events kept for Last-Event-ID replay
+	eventClientBufferSize = 32               // Events queued per client before it is disconnected as lagging
+	sseHeartbeatInterval  = 15 * time.Second // Comment lines that keep proxies from closing idle streams
+	sseRetryMs            = 2000             // Reconnection delay suggested to EventSource
+)
+
+// uiEvent is a message broadcast to UI clients, with its SSE event ID.
+type uiEvent struct {
+	ID   uint64
+	Data []byte
+}
+
+// eventLog numbers broadcast events and keeps the most recent ones so that
+// reconnecting SSE clients can catch up.
+type eventLog struct {
+	sync.Mutex
+	lastID uint64
+	events []uiEvent // Oldest first, at most eventReplaySize
+}
+
+// uiEventData is the JSON payload of a UI event. SSE clients receive it as
+// is, WebSocket clients as the matching wsMessage.
+type uiEventData struct {
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (d uiEventData) marshal() []byte {
+	data, _ := json.Marshal(d) // Cannot fail for a struct of strings
+	return data
+}
+
+// promptEventData is the broadcast that shows a prompt in the UI.
+func promptEventData(id, prompt, title string) []byte {
+	return uiEventData{Type: "prompt", ID: id, Prompt: prompt, Title: title}.marshal()
+}
+
+// reminderEventData is the broadcast that reminds the user of the prompt.
+func reminderEventData(prompt, title string) []byte {
+	return uiEventData{Type: "reminder", Prompt: prompt, Title: title}.marshal()
+}
+
+// closeEventData is the broadcast that removes a prompt from the UI.
+func closeEventData(id, reason string) []byte {
+	return uiEventData{Type: "close", ID: id, Reason: reason}.marshal()
+}
+
+// broadcastSSEMessage sends message to every UI client. A client whose buffer
+// is full is disconnected rather than silently missing the event; SSE
+// clients then reconnect and replay it via Last-Event-ID.
+func (s *Server) broadcastSSEMessage(message []byte) {
+	s.events.Lock()
+	defer s.events.Unlock()
+
+	s.events.lastID++
+	event := uiEvent{ID: s.events.lastID, Data: message}
+	s.events.events = append(s.events.events, event)
+	if len(s.events.events) > eventReplaySize {
+		s.events.events = s.events.events[len(s.events.events)-eventReplaySize:]
+	}
+
+	s.log.Debug("Broadcasting UI event", "event_id", event.ID)
+	s.clients.broadcast(event, func(client *uiClient) {
+		s.log.Warn("UI client is not keeping up, disconnecting it to replay missed events", "client_id", client.ID)
+		s.metrics.broadcastDropped()
+	})
+}
+
+// subscribeEvents registers a UI client and returns the events to send it
+// first: the events after lastEventID if they are all still buffered,
+// otherwise the active prompt, if any.
+func (s *Server) subscribeEvents(client *uiClient, lastEventID string) []uiEvent {
+	// Same lock order as closePrompt, which broadcasts with the prompt locked.
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+	s.events.Lock()
+	defer s.events.Unlock()
+
+	s.clients.register(client)
+
+	if lastEventID != "" {
+		if missed, ok := s.events.afterLocked(lastEventID); ok {
+			s.log.Info("Replaying missed events to UI client", "client_id", client.ID, "events", len(missed), "last_event_id", lastEventID)
+			return missed
+		}
+		s.log.Info("Cannot replay missed events to UI client, sending current state", "client_id", client.ID, "last_event_id", lastEventID)
+	}
+
+	var initial []uiEvent
+	if details := s.prompt.details; details != nil && details.IsActive {
+		initial = append(initial, uiEvent{ID: s.events.lastID, Data: promptEventData(details.ID, details.Prompt, details.Title)})
+	}
+	return initial
+}
+
+// afterLocked returns the buffered events after lastEventID, or false if
+// some of them are no longer buffered or the ID is from before a restart.
+// The log must be locked.
+func (l *eventLog) afterLocked(lastEventID string) ([]uiEvent, bool) {
+	lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil || lastID > l.lastID {
+		return nil, false
+	}
+	if lastID == l.lastID {
+		return nil, true
+	}
+	if len(l.events) == 0 || l.events[0].ID > lastID+1 {
+		return nil, false
+	}
+	missed := l.events[lastID+1-l.events[0].ID:]
+	return append([]uiEvent(nil), missed...), true
+}
+
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	client := newUIClient(r, transportSSE)
+	clientKey := client.ID
+	initial := s.subscribeEvents(client, r.Header.Get("Last-Event-ID"))
+	log := s.log.With("client_id", clientKey, "transport", transportSSE)
+	log.Info("UI client connected", "remote_addr", r.RemoteAddr, "user_agent", r.UserAgent())
+	defer func() {
+		s.clients.unregister(client)
+		log.Info("UI client disconnected")
+	}()
+
+	// Send missed events, or the current prompt if one is active
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMs)
+	for _, event := range initial {
+		log.Debug("Sending initial event", "event_id", event.ID)
+		writeSSEEvent(w, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Keep connection open and send messages
+	for {
+		select {
+		case event := <-client.events:
+			log.Debug("Sending event", "event_id", event.ID)
+			writeSSEEvent(w, event)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-client.lagged:
+			// Ending the stream makes EventSource reconnect with Last-Event-ID.
+			log.Debug("UI client fell behind, closing the stream")
+			return
+		case <-s.done:
+			log.Debug("Server shutting down, closing the stream")
+			return
+		case <-r.Context().Done(): // Client disconnected OR server shutting down connection
+			log.Debug("Request context done, closing the stream", "error", r.Context().Err())
+			return // Exit handler, which triggers defer
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event uiEvent) {
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, event.Data)
+}
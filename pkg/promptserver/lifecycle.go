@@ -0,0 +1,67 @@
+package promptserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// HealthResponse is the response of GET /api/health.
+type HealthResponse struct {
+	Status string `json:"status"` // "ok", or "shutting_down" with status 503
+	PID    int    `json:"pid"`
+}
+
+// healthHandler lets clients check that the server is up, e.g. before
+// starting one of their own.
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := HealthResponse{Status: "ok", PID: os.Getpid()}
+	select {
+	case <-s.done:
+		resp.Status = protocol.ErrorCodeShuttingDown
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Idle returns a channel that is closed once the server has gone
+// Options.IdleShutdown without showing a prompt, so that its owner can shut
+// it down. It is never closed if IdleShutdown is zero.
+func (s *Server) Idle() <-chan struct{} {
+	return s.idle
+}
+
+func (s *Server) watchIdle() {
+	timer := time.NewTimer(s.opts.IdleShutdown)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-timer.C:
+		}
+
+		if remaining := s.opts.IdleShutdown - s.idleFor(time.Now()); remaining > 0 {
+			timer.Reset(remaining)
+			continue
+		}
+		s.log.Info("Server is idle", "idle_for", s.opts.IdleShutdown)
+		close(s.idle)
+		return
+	}
+}
+
+// idleFor returns how long no prompt has been shown.
+func (s *Server) idleFor(now time.Time) time.Duration {
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+	if s.prompt.details != nil && s.prompt.details.IsActive {
+		return 0
+	}
+	return now.Sub(s.prompt.lastActive)
+}
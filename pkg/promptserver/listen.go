@@ -0,0 +1,71 @@
+package promptserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// DefaultSocketMode lets only the user running the server connect to its
+// Unix domain socket.
+const DefaultSocketMode os.FileMode = 0o600
+
+// Listen opens a listener on addr: a Unix domain socket for "unix:" addresses
+// (see protocol.SocketPath), otherwise a TCP host:port such as ":3030".
+//
+// Access to a socket is controlled by file permissions: the socket is
+// created accessible to its owner only, then set to socketMode, and a missing parent directory is created
+// accessible to its owner only. A stale socket left behind by a server that
+// did not shut down cleanly is replaced; a socket another server is still
+// listening on is an error. The socket file is removed when the listener is
+// closed.
+func Listen(addr string, socketMode os.FileMode) (net.Listener, error) {
+	path, ok := protocol.SocketPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invalid socket address %q: missing path", addr)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	l, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket at path unless a server is listening
+// on it. Files other than sockets are never removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
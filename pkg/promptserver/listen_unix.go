@@ -0,0 +1,18 @@
+//go:build !windows
+
+package promptserver
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix listens on the Unix domain socket at path. The socket is created
+// under a umask that leaves it accessible to its owner only, so that it is
+// never open to others before Listen applies the socket mode. The umask is
+// process-wide, which is why Listen belongs in the server's startup.
+func listenUnix(path string) (net.Listener, error) {
+	umask := syscall.Umask(0o177)
+	defer syscall.Umask(umask)
+	return net.Listen("unix", path)
+}
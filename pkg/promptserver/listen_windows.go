@@ -0,0 +1,11 @@
+//go:build windows
+
+package promptserver
+
+import "net"
+
+// listenUnix listens on the Unix domain socket at path. Windows has no
+// umask; the socket inherits the access control list of its directory.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
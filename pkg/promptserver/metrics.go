@@ -0,0 +1,150 @@
+package promptserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Outcomes of a prompt request, the outcome label of the prompt metrics.
+// They follow the ways triggerPromptHandler can return.
+const (
+	outcomeAnswered     = "answered"
+	outcomeTimedOut     = "timed_out"
+	outcomeCancelled    = "cancelled" // The client gave up waiting
+	outcomeDismissed    = "dismissed" // The user dismissed the prompt
+	outcomeNoUI         = "no_ui_connected"
+	outcomeShuttingDown = "shutting_down"
+	outcomeConflict     = "conflict" // Rejected while another prompt was active
+	outcomeError        = "error"
+)
+
+// promptOutcomes lists the outcomes in the order they are exposed.
+var promptOutcomes = []string{
+	outcomeAnswered, outcomeTimedOut, outcomeCancelled, outcomeDismissed,
+	outcomeNoUI, outcomeShuttingDown, outcomeConflict, outcomeError,
+}
+
+// waitBuckets are the upper bounds, in seconds, of the prompt wait histogram:
+// from a quick reply to a prompt left open for an hour.
+var waitBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 3600}
+
+// histogram is a cumulative Prometheus-style histogram over waitBuckets.
+type histogram struct {
+	buckets []uint64 // buckets[i] counts observations <= waitBuckets[i]
+	count   uint64
+	sum     float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range waitBuckets {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics holds the counters exposed at /metrics. Gauges such as the
+// connected clients are read from the server when scraped.
+type metrics struct {
+	mu                sync.Mutex
+	triggered         uint64
+	outcomes          map[string]uint64
+	waits             map[string]*histogram
+	droppedBroadcasts uint64
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		outcomes: make(map[string]uint64),
+		waits:    make(map[string]*histogram),
+	}
+	for _, outcome := range promptOutcomes {
+		m.waits[outcome] = &histogram{buckets: make([]uint64, len(waitBuckets))}
+	}
+	return m
+}
+
+func (m *metrics) promptTriggered() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.triggered++
+}
+
+// promptFinished records how a prompt request ended and how long the caller
+// waited for it.
+func (m *metrics) promptFinished(outcome string, waited time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes[outcome]++
+	m.waits[outcome].observe(waited.Seconds())
+}
+
+func (m *metrics) broadcastDropped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.droppedBroadcasts++
+}
+
+// metricsHandler serves GET /metrics in the Prometheus text format.
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	activePrompts, queuedPrompts := 0, 0
+	s.prompt.Lock()
+	if s.prompt.details != nil && s.prompt.details.IsActive {
+		activePrompts, queuedPrompts = 1, s.prompt.details.Queued
+	}
+	s.prompt.Unlock()
+	clientsByTransport := map[string]int{transportSSE: 0, transportWebSocket: 0}
+	for _, client := range s.clients.list() {
+		clientsByTransport[client.Transport]++
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m := s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeMetricHeader(w, "user_prompt_prompts_triggered_total", "counter", "Prompt requests received by /api/trigger-prompt.")
+	fmt.Fprintf(w, "user_prompt_prompts_triggered_total %d\n", m.triggered)
+
+	writeMetricHeader(w, "user_prompt_prompts_total", "counter", "Prompt requests that ended, by outcome.")
+	for _, outcome := range promptOutcomes {
+		fmt.Fprintf(w, "user_prompt_prompts_total{outcome=%q} %d\n", outcome, m.outcomes[outcome])
+	}
+
+	writeMetricHeader(w, "user_prompt_wait_seconds", "histogram", "How long callers waited for a prompt request to end, by outcome.")
+	for _, outcome := range promptOutcomes {
+		h := m.waits[outcome]
+		for i, bound := range waitBuckets {
+			fmt.Fprintf(w, "user_prompt_wait_seconds_bucket{outcome=%q,le=%q} %d\n", outcome, strconv.FormatFloat(bound, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(w, "user_prompt_wait_seconds_bucket{outcome=%q,le=\"+Inf\"} %d\n", outcome, h.count)
+		fmt.Fprintf(w, "user_prompt_wait_seconds_sum{outcome=%q} %s\n", outcome, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "user_prompt_wait_seconds_count{outcome=%q} %d\n", outcome, h.count)
+	}
+
+	writeMetricHeader(w, "user_prompt_active_prompts", "gauge", "Prompts being shown; the server shows one at a time and rejects others with a conflict.")
+	fmt.Fprintf(w, "user_prompt_active_prompts %d\n", activePrompts)
+
+	// The server has no queue of its own: prompts wait in the client, which
+	// reports its queue length with each prompt it sends.
+	writeMetricHeader(w, "user_prompt_queued_prompts", "gauge", "Prompts waiting in the client behind the active prompt, as reported when it was sent.")
+	fmt.Fprintf(w, "user_prompt_queued_prompts %d\n", queuedPrompts)
+
+	writeMetricHeader(w, "user_prompt_ui_clients", "gauge", "Connected UI clients, by transport.")
+	for _, transport := range []string{transportSSE, transportWebSocket} {
+		fmt.Fprintf(w, "user_prompt_ui_clients{transport=%q} %d\n", transport, clientsByTransport[transport])
+	}
+
+	writeMetricHeader(w, "user_prompt_dropped_broadcasts_total", "counter", "UI clients disconnected because they fell behind on events.")
+	fmt.Fprintf(w, "user_prompt_dropped_broadcasts_total %d\n", m.droppedBroadcasts)
+}
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -0,0 +1,71 @@
+package promptserver
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/shell"
+)
+
+// notifyCommandTimeout bounds how long a notification hook may run.
+const notifyCommandTimeout = 10 * time.Second
+
+// Notification events passed to the notification hooks.
+const (
+	notifyEventPrompt   = "prompt"
+	notifyEventReminder = "reminder"
+)
+
+// notifier runs server-side notification hooks when a prompt is triggered
+// and when it stays unanswered for too long.
+type notifier struct {
+	// command is run through the system shell. The prompt details are passed
+	// in the USER_PROMPT_EVENT, USER_PROMPT_TITLE and USER_PROMPT_TEXT
+	// environment variables rather than substituted into the command, so the
+	// prompt text can never be interpreted by the shell.
+	command string
+	// notifySend runs `notify-send` with the prompt title and text.
+	notifySend bool
+	// remindAfter is the interval after which an unanswered prompt triggers
+	// a reminder. Zero disables reminders.
+	remindAfter time.Duration
+	log         *slog.Logger
+}
+
+// enabled reports whether any server-side hook is configured.
+func (n *notifier) enabled() bool {
+	return n.command != "" || n.notifySend
+}
+
+// notify runs the configured hooks in the background.
+func (n *notifier) notify(event, title, prompt string) {
+	if !n.enabled() {
+		return
+	}
+	if n.notifySend {
+		// "--" keeps a title or prompt starting with "-" from being parsed
+		// as an option.
+		go n.runHook(event, nil, "notify-send", "--app-name=User Prompt", "--", title, prompt)
+	}
+	if n.command != "" {
+		env := []string{"USER_PROMPT_EVENT=" + event, "USER_PROMPT_TITLE=" + title, "USER_PROMPT_TEXT=" + prompt}
+		shellName, args := shell.Command(n.command)
+		go n.runHook(event, env, shellName, args...)
+	}
+}
+
+func (n *notifier) runHook(event string, env []string, name string, args ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		n.log.Warn("Notification hook failed", "event", event, "hook", name, "error", err, "output", string(output))
+		return
+	}
+	n.log.Debug("Notification hook completed", "event", event, "hook", name)
+}
@@ -0,0 +1,96 @@
+package promptserver
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// hookLog returns a notify command that appends the hook environment to a
+// file, and a function reading the lines written so far.
+func hookLog(t *testing.T) (string, func() []string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("The test hook is a POSIX shell command")
+	}
+	path := filepath.Join(t.TempDir(), "hook.log")
+	command := `printf '%s|%s|%s\n' "$USER_PROMPT_EVENT" "$USER_PROMPT_TITLE" "$USER_PROMPT_TEXT" >> '` + path + `'`
+	return command, func() []string {
+		data, _ := os.ReadFile(path)
+		if len(data) == 0 {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+}
+
+// waitHookLines waits until the hook has written at least n lines.
+func waitHookLines(t *testing.T, lines func() []string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := lines(); len(got) >= n {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d hook runs, got %d", n, len(lines()))
+	return nil
+}
+
+func TestNotifyCommandEnvironment(t *testing.T) {
+	command, lines := hookLog(t)
+	opts := DefaultOptions()
+	opts.NotifyCommand = command
+	_, ts := newTestServer(t, opts)
+
+	// Shell syntax in the prompt must reach the hook verbatim.
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: `Run $(rm -rf /)?`, Title: "Deploy; exit 1", TimeoutMs: 5000})
+	got := waitHookLines(t, lines, 1)
+	if want := "prompt|Deploy; exit 1|Run $(rm -rf /)?"; got[0] != want {
+		t.Errorf("Expected hook environment %q, got %q", want, got[0])
+	}
+
+	http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "no", "prompt_id": "p1"}`))
+	waitResult(t, result)
+}
+
+func TestReminderUntilAnswered(t *testing.T) {
+	command, lines := hookLog(t)
+	opts := DefaultOptions()
+	opts.NotifyCommand = command
+	opts.RemindAfter = 100 * time.Millisecond
+	_, ts := newTestServer(t, opts)
+
+	start := time.Now()
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", Title: "Test", TimeoutMs: 5000})
+	got := waitHookLines(t, lines, 2)
+	if elapsed := time.Since(start); elapsed < opts.RemindAfter {
+		t.Errorf("Expected the reminder after %v, got it after %v", opts.RemindAfter, elapsed)
+	}
+	if got[0] != "prompt|Test|Continue?" || got[1] != "reminder|Test|Continue?" {
+		t.Errorf("Expected a prompt and a reminder notification, got %q", got)
+	}
+
+	resp, err := http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "yes", "prompt_id": "p1"}`))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	resp.Body.Close()
+	waitResult(t, result)
+
+	// Let a hook started just before the answer finish, then make sure no
+	// more reminders follow.
+	time.Sleep(opts.RemindAfter)
+	answered := len(lines())
+	time.Sleep(3 * opts.RemindAfter)
+	if after := len(lines()); after != answered {
+		t.Errorf("Expected no reminders after the answer, got %d more", after-answered)
+	}
+}
@@ -0,0 +1,119 @@
+package promptserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// typingIdleTimeout is how long after the last typing event the user is no
+// longer considered to be typing.
+const typingIdleTimeout = 5 * time.Second
+
+// Presence events reported by the Vibeframe page.
+const (
+	presenceEventViewed    = "viewed"
+	presenceEventTyping    = "typing"
+	presenceEventDelivered = "delivered" // Acknowledged by a WebSocket client
+)
+
+var errUnknownPresenceEvent = errors.New("unknown presence event")
+
+// presenceLocked returns the presence of the prompt. The prompt state must
+// be locked.
+func (p *activePrompt) presenceLocked(now time.Time, uiClients int) protocol.PromptPresence {
+	presence := protocol.PromptPresence{
+		UIClients: uiClients,
+		Delivered: !p.DeliveredAt.IsZero() || !p.ViewedAt.IsZero(),
+		Typing:    !p.LastTypingAt.IsZero() && now.Sub(p.LastTypingAt) < typingIdleTimeout,
+	}
+	if !p.ViewedAt.IsZero() {
+		viewedAt := p.ViewedAt
+		presence.Viewed = true
+		presence.ViewedAt = &viewedAt
+	}
+	return presence
+}
+
+// presenceHandler records view and typing events from the Vibeframe page.
+func (s *Server) presenceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data struct {
+		PromptID string `json:"prompt_id"`
+		Event    string `json:"event"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		s.log.Warn("Invalid /api/presence payload", "error", err)
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	switch err := s.recordPresence(data.PromptID, data.Event); {
+	case errors.Is(err, errPromptNotPending):
+		http.Error(w, "No such active prompt", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, "Unknown presence event", http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// recordPresence records a presence event for the active prompt.
+func (s *Server) recordPresence(promptID, event string) error {
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+
+	details := s.prompt.details
+	if details == nil || !details.IsActive || details.ID != promptID {
+		return errPromptNotPending
+	}
+	now := time.Now().UTC()
+	switch event {
+	case presenceEventDelivered:
+		if details.DeliveredAt.IsZero() {
+			s.log.Info("Prompt delivered to the UI", "prompt_id", details.ID)
+			details.DeliveredAt = now
+		}
+	case presenceEventViewed:
+		if details.ViewedAt.IsZero() {
+			s.log.Info("Prompt viewed by the user", "prompt_id", details.ID)
+			details.ViewedAt = now
+		}
+	case presenceEventTyping:
+		details.LastTypingAt = now
+	default:
+		return fmt.Errorf("%w %q", errUnknownPresenceEvent, event)
+	}
+	return nil
+}
+
+// promptStatusHandler serves GET /api/prompts/{id}/status so the waiting
+// client can report whether the user has seen the prompt.
+func (s *Server) promptStatusHandler(w http.ResponseWriter, r *http.Request) {
+	promptID := r.PathValue("id")
+
+	s.prompt.Lock()
+	details := s.prompt.details
+	if details == nil || details.ID != promptID {
+		s.prompt.Unlock()
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+	status := protocol.PromptStatus{
+		ID:             details.ID,
+		Active:         details.IsActive,
+		PromptPresence: details.presenceLocked(time.Now(), s.clients.count()),
+	}
+	s.prompt.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
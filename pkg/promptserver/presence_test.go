@@ -0,0 +1,94 @@
+package promptserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// postPresence reports a presence event and returns the response status.
+func postPresence(t *testing.T, ts *httptest.Server, body string) int {
+	t.Helper()
+	resp, err := http.Post(ts.URL+"/api/presence", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Presence request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPresence(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	openSSE(t, ts, "")
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+
+	status := promptStatus(t, ts, "p1")
+	if status.ID != "p1" || !status.Active || status.UIClients != 1 || status.Delivered || status.Viewed || status.Typing {
+		t.Errorf("Expected an unseen active prompt with 1 UI client, got %+v", status)
+	}
+
+	if code := postPresence(t, ts, `{"prompt_id": "p1", "event": "viewed"}`); code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 for viewed, got %d", code)
+	}
+	status = promptStatus(t, ts, "p1")
+	if !status.Viewed || status.ViewedAt == nil || !status.Delivered || status.Typing {
+		t.Errorf("Expected a viewed prompt, got %+v", status)
+	}
+	viewedAt := *status.ViewedAt
+
+	if code := postPresence(t, ts, `{"prompt_id": "p1", "event": "typing"}`); code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 for typing, got %d", code)
+	}
+	postPresence(t, ts, `{"prompt_id": "p1", "event": "viewed"}`)
+	status = promptStatus(t, ts, "p1")
+	if !status.Typing || !status.ViewedAt.Equal(viewedAt) {
+		t.Errorf("Expected a typing user and the first view time %v, got %+v", viewedAt, status)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"stale prompt", `{"prompt_id": "old", "event": "viewed"}`, http.StatusNotFound},
+		{"unknown event", `{"prompt_id": "p1", "event": "asleep"}`, http.StatusBadRequest},
+		{"invalid JSON", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := postPresence(t, ts, tt.body); code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.code, code)
+		}
+	}
+
+	http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "yes", "prompt_id": "p1"}`))
+	r := waitResult(t, result)
+	if p := r.resp.Presence; p == nil || !p.Viewed || !p.Typing {
+		t.Errorf("Expected the presence in the response, got %+v", p)
+	}
+
+	// The finished prompt keeps its status until the next one replaces it,
+	// but no longer accepts presence events.
+	if status := promptStatus(t, ts, "p1"); status.Active || !status.Viewed {
+		t.Errorf("Expected an inactive, viewed prompt, got %+v", status)
+	}
+	if code := postPresence(t, ts, `{"prompt_id": "p1", "event": "typing"}`); code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a finished prompt, got %d", code)
+	}
+}
+
+func TestPromptStatusNotFound(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+
+	resp, err := http.Get(ts.URL + "/api/prompts/missing/status")
+	if err != nil {
+		t.Fatalf("Status request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
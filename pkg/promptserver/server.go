@@ -0,0 +1,553 @@
+// Package promptserver implements user-prompt-server: an HTTP server that
+// shows prompts from user-prompt-mcp in the Vibeframe web UI and returns the
+// user's answers.
+package promptserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nazar256/user-prompt-mcp/internal/logging"
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/nazar256/user-prompt-mcp/pkg/promptserver"
+
+// Policies for prompts triggered while no UI client is connected.
+const (
+	NoUIPolicyWait = "wait" // Wait for the prompt timeout, as if a UI were connected
+	NoUIPolicyFail = "fail" // Fail once the grace period passes without a UI connecting
+)
+
+// Vibeframe color themes.
+const (
+	UIThemeAuto  = "auto" // Follow the browser's preference
+	UIThemeDark  = "dark"
+	UIThemeLight = "light"
+)
+
+// Options contains options for creating a new Server
+type Options struct {
+	// DefaultTimeout applies to prompts triggered without a timeout.
+	DefaultTimeout time.Duration
+	// APIToken authenticates POST /api/prompts/{id}/answer. Empty disables
+	// the answer API.
+	APIToken string
+	// NoUIPolicy and NoUIGrace decide what happens to a prompt while no UI
+	// is connected. Clients may override them per prompt.
+	NoUIPolicy string
+	NoUIGrace  time.Duration
+	// NotifyCommand is run through the system shell when a prompt arrives,
+	// with the prompt details in USER_PROMPT_EVENT, USER_PROMPT_TITLE and
+	// USER_PROMPT_TEXT.
+	NotifyCommand string
+	// NotifySend shows a desktop notification via notify-send.
+	NotifySend bool
+	// RemindAfter is the interval at which an unanswered prompt triggers a
+	// reminder. Zero disables reminders.
+	RemindAfter time.Duration
+	// WebhookURL receives every prompt lifecycle event. Empty disables the
+	// webhook.
+	WebhookURL     string
+	WebhookSecret  string
+	WebhookRetries int
+	// UITheme is the Vibeframe color theme: UIThemeAuto, UIThemeDark or
+	// UIThemeLight.
+	UITheme string
+	// UIBrowserNotifications lets the Vibeframe page show browser
+	// notifications for prompts that arrive while it is in the background.
+	UIBrowserNotifications bool
+	// IdleShutdown is how long the server may go without a prompt before
+	// Idle reports it idle. Zero disables idle detection.
+	IdleShutdown time.Duration
+	// Logger receives the server's logs; nil uses slog.Default(). Prompt
+	// content is logged with the logging package's attributes.
+	Logger *slog.Logger
+	// TracerProvider and Propagator trace the wait for each answer as part
+	// of the client's trace; nil uses the global ones at span start.
+	TracerProvider trace.TracerProvider
+	Propagator     propagation.TextMapPropagator
+}
+
+// DefaultOptions returns the default options for the prompt server
+func DefaultOptions() Options {
+	return Options{
+		DefaultTimeout:         20 * time.Minute,
+		NoUIPolicy:             NoUIPolicyWait,
+		WebhookRetries:         3,
+		UITheme:                UIThemeAuto,
+		UIBrowserNotifications: true,
+	}
+}
+
+// Server is a prompt server. It shows one prompt at a time.
+type Server struct {
+	opts     Options
+	prompt   promptState
+	events   eventLog
+	clients  *clientRegistry
+	notifier *notifier
+	webhook  *webhookSink // nil unless Options.WebhookURL is set
+	metrics  *metrics
+	log      *slog.Logger
+
+	httpServer   *http.Server
+	idle         chan struct{} // Closed once the server has been idle for IdleShutdown
+	done         chan struct{} // Closed by Shutdown
+	shutdownOnce sync.Once
+}
+
+// New creates a new prompt server with the given options
+func New(opts Options) (*Server, error) {
+	if opts.DefaultTimeout <= 0 {
+		opts.DefaultTimeout = DefaultOptions().DefaultTimeout
+	}
+	switch opts.NoUIPolicy {
+	case "":
+		opts.NoUIPolicy = NoUIPolicyWait
+	case NoUIPolicyWait, NoUIPolicyFail:
+	default:
+		return nil, fmt.Errorf("invalid no-UI policy %q, expected %q or %q", opts.NoUIPolicy, NoUIPolicyWait, NoUIPolicyFail)
+	}
+	switch opts.UITheme {
+	case "":
+		opts.UITheme = UIThemeAuto
+	case UIThemeAuto, UIThemeDark, UIThemeLight:
+	default:
+		return nil, fmt.Errorf("invalid UI theme %q, expected %q, %q or %q", opts.UITheme, UIThemeAuto, UIThemeDark, UIThemeLight)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &Server{
+		opts:    opts,
+		log:     logger,
+		clients: newClientRegistry(),
+		notifier: &notifier{
+			command:     opts.NotifyCommand,
+			notifySend:  opts.NotifySend,
+			remindAfter: opts.RemindAfter,
+			log:         logger,
+		},
+		metrics: newMetrics(),
+		idle:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.prompt.lastActive = time.Now()
+	if opts.WebhookURL != "" {
+		s.webhook = newWebhookSink(opts.WebhookURL, opts.WebhookSecret, opts.WebhookRetries, logger)
+	}
+	if opts.IdleShutdown > 0 {
+		go s.watchIdle()
+	}
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the Vibeframe UI and the API, for
+// embedding the prompt server in another HTTP server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vibeframe", s.vibeframeHandler)
+	mux.HandleFunc("/events", s.eventsHandler)
+	mux.HandleFunc("/ws", s.websocketHandler)
+	mux.HandleFunc("/submit-input", s.submitInputHandler)
+	mux.HandleFunc("/api/trigger-prompt", s.triggerPromptHandler)
+	mux.HandleFunc("POST /api/prompts/{id}/answer", s.answerAPIHandler)
+	mux.HandleFunc("GET /api/prompts/{id}/status", s.promptStatusHandler)
+	mux.HandleFunc("/api/presence", s.presenceHandler)
+	mux.HandleFunc("GET /api/clients", s.clientsHandler)
+	mux.HandleFunc("GET /api/health", s.healthHandler)
+	mux.HandleFunc("GET /metrics", s.metricsHandler)
+	return mux
+}
+
+// ListenAndServe serves the prompt server on addr, a TCP address or a Unix
+// domain socket URL (see Listen), over TLS if certFile and keyFile are set.
+// After Shutdown it returns http.ErrServerClosed.
+func (s *Server) ListenAndServe(addr, certFile, keyFile string) error {
+	l, err := Listen(addr, DefaultSocketMode)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l, certFile, keyFile)
+}
+
+// Serve serves the prompt server on l, over TLS if certFile and keyFile are
+// set. It may be called for several listeners; Shutdown closes all of them.
+// After Shutdown it returns http.ErrServerClosed.
+func (s *Server) Serve(l net.Listener, certFile, keyFile string) error {
+	if certFile != "" && keyFile != "" {
+		return s.httpServer.ServeTLS(l, certFile, keyFile)
+	}
+	return s.httpServer.Serve(l)
+}
+
+// Shutdown fails the pending prompt, disconnects the UI clients, stops the
+// HTTP server and delivers the queued webhook events, until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() { close(s.done) })
+
+	err := s.httpServer.Shutdown(ctx)
+	if webhookErr := s.webhook.close(ctx); err == nil {
+		err = webhookErr
+	}
+	return err
+}
+
+// --- Structures to manage the active prompt for Vibeframe ---
+type activePrompt struct {
+	ID           string
+	Prompt       string
+	Title        string
+	ResponseChan chan string // Channel to send the user's response back
+	ErrorChan    chan error  // Channel to send an error
+	IsActive     bool
+	DeliveredAt  time.Time // When a WebSocket client first acknowledged the prompt
+	ViewedAt     time.Time // When the UI first reported the prompt as visible
+	LastTypingAt time.Time // When the UI last reported typing
+	Queued       int       // Prompts waiting in the client behind this one
+}
+
+type promptState struct {
+	sync.Mutex
+	details    *activePrompt
+	lastActive time.Time // When a prompt was last shown, for idle detection
+}
+
+// --- End of Vibeframe prompt state ---
+
+func (s *Server) submitInputHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*") // For webview
+
+	var data struct {
+		Input    string `json:"input"`
+		PromptID string `json:"prompt_id"` // Optional for older UIs; when set it must match the active prompt
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		s.log.Warn("Invalid /submit-input payload", "error", err)
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.answerPrompt(data.PromptID, data.Input); err != nil {
+		s.log.Warn("Received an answer that could not be delivered", "prompt_id", data.PromptID, "error", err)
+		http.Error(w, "No active prompt or prompt already handled", http.StatusConflict)
+		return
+	}
+	s.log.Info("Received answer", "prompt_id", data.PromptID, logging.Answer(data.Input))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Input received by server."))
+}
+
+var errPromptNotPending = errors.New("prompt not found or no longer pending")
+
+// answerPrompt delivers input to the active prompt. An empty promptID
+// matches whichever prompt is active. The prompt is marked inactive in the
+// same critical section, so a prompt can only ever be answered once.
+func (s *Server) answerPrompt(promptID, input string) error {
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+
+	details := s.prompt.details
+	if details == nil || !details.IsActive || (promptID != "" && promptID != details.ID) {
+		return errPromptNotPending
+	}
+	details.IsActive = false
+	details.ResponseChan <- input // Buffered, never blocks for the single answer
+	return nil
+}
+
+// answerAPIHandler serves POST /api/prompts/{id}/answer for external
+// integrations such as chat bots. Requests must carry Options.APIToken as a
+// bearer token.
+func (s *Server) answerAPIHandler(w http.ResponseWriter, r *http.Request) {
+	promptID := r.PathValue("id")
+	s.log.Debug("Received answer API request", "prompt_id", promptID)
+
+	if s.opts.APIToken == "" {
+		http.Error(w, "Answer API is disabled; start the server with --api-token to enable it", http.StatusNotFound)
+		return
+	}
+	if !validBearerToken(r, s.opts.APIToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="user-prompt-server"`)
+		http.Error(w, "Invalid or missing API token", http.StatusUnauthorized)
+		return
+	}
+
+	var data struct {
+		Input string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		s.log.Warn("Invalid answer API payload", "prompt_id", promptID, "error", err)
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.answerPrompt(promptID, data.Input); err != nil {
+		s.log.Warn("Could not answer prompt via the API", "prompt_id", promptID, "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"prompt_id": promptID, "error": err.Error()})
+		return
+	}
+	// The UI did not submit this answer, so tell it the prompt is gone.
+	s.broadcastSSEMessage(closeEventData(promptID, "answered via API"))
+	json.NewEncoder(w).Encode(map[string]string{"prompt_id": promptID, "status": "answered"})
+}
+
+func validBearerToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// --- API Handler for triggering prompts ---
+func (s *Server) triggerPromptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req protocol.TriggerPromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.log.Warn("Invalid /api/trigger-prompt payload", "error", err)
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	noUIPolicy, noUIGrace := s.opts.NoUIPolicy, s.opts.NoUIGrace
+	switch req.NoUIPolicy {
+	case "":
+	case NoUIPolicyWait, NoUIPolicyFail:
+		noUIPolicy = req.NoUIPolicy
+		noUIGrace = time.Duration(req.NoUIGraceMs) * time.Millisecond
+	default:
+		http.Error(w, "Invalid no_ui_policy, expected 'wait' or 'fail'", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	s.metrics.promptTriggered()
+	requestedAt := time.Now()
+
+	// The span covers the wait for the user, as part of the client's trace
+	// when it sent a traceparent header.
+	ctx := s.propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	_, span := s.tracer().Start(ctx, "promptserver.wait", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	s.prompt.Lock()
+	if s.prompt.details != nil && s.prompt.details.IsActive {
+		s.prompt.Unlock()
+		s.metrics.promptFinished(outcomeConflict, time.Since(requestedAt))
+		span.SetAttributes(attribute.String("prompt.outcome", outcomeConflict))
+		span.SetStatus(codes.Error, "another prompt is already active")
+		s.log.Warn("Rejected prompt, another prompt is already active", logging.Title(req.Title))
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(protocol.TriggerPromptResponse{Error: "Another prompt is already active", Code: protocol.ErrorCodePromptConflict})
+		return
+	}
+
+	responseChan := make(chan string, 1)
+	errorChan := make(chan error, 1)
+	promptID := req.ID
+	if promptID == "" {
+		promptID = uuid.NewString()
+	}
+	s.prompt.details = &activePrompt{
+		ID:           promptID,
+		Prompt:       req.Prompt,
+		Title:        req.Title,
+		ResponseChan: responseChan,
+		ErrorChan:    errorChan,
+		IsActive:     true,
+		Queued:       max(req.Queued, 0),
+	}
+	s.prompt.lastActive = time.Now()
+	s.prompt.Unlock() // Unlock before broadcasting and waiting
+	log := s.log.With("prompt_id", promptID)
+	span.SetAttributes(attribute.String("prompt.id", promptID))
+	log.Info("Prompt requested", logging.Title(req.Title), logging.Prompt(req.Prompt), "timeout_ms", req.TimeoutMs)
+
+	s.broadcastSSEMessage(promptEventData(promptID, req.Prompt, req.Title))
+	s.notifier.notify(notifyEventPrompt, req.Title, req.Prompt)
+	s.webhook.send(WebhookEvent{Event: promptEventCreated, PromptID: promptID, Title: req.Title, Prompt: req.Prompt})
+
+	timeoutDuration := s.opts.DefaultTimeout
+	if req.TimeoutMs > 0 {
+		timeoutDuration = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+
+	// A nil channel never fires, so reminders are simply skipped when disabled.
+	var remindCh <-chan time.Time
+	if s.notifier.remindAfter > 0 {
+		reminder := time.NewTicker(s.notifier.remindAfter)
+		defer reminder.Stop()
+		remindCh = reminder.C
+	}
+	timeout := time.NewTimer(timeoutDuration)
+	defer timeout.Stop()
+
+	// With the fail policy, give a UI the grace period to connect and then
+	// give up rather than wait for a prompt nobody can see.
+	var noUICh <-chan time.Time
+	if noUIPolicy == NoUIPolicyFail && s.clients.count() == 0 {
+		log.Info("No UI client connected, waiting for one", "grace", noUIGrace)
+		noUITimer := time.NewTimer(noUIGrace)
+		defer noUITimer.Stop()
+		noUICh = noUITimer.C
+	}
+
+	var resp protocol.TriggerPromptResponse
+	var outcome string
+wait:
+	for {
+		select {
+		case input := <-responseChan:
+			log.Info("Prompt answered", logging.Answer(input), "waited", time.Since(requestedAt))
+			resp.Input = input
+			outcome = outcomeAnswered
+			w.WriteHeader(http.StatusOK)
+			s.webhook.send(WebhookEvent{Event: promptEventAnswered, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Input: input})
+			break wait
+		case err := <-errorChan: // The user dismissed the prompt, see dismissPrompt
+			log.Info("Prompt failed", "error", err)
+			resp.Error = err.Error()
+			if errors.Is(err, errPromptDismissed) {
+				resp.Code = protocol.ErrorCodeDismissed
+				outcome = outcomeDismissed
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				outcome = outcomeError
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			s.webhook.send(WebhookEvent{Event: promptEventCancelled, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Error: resp.Error})
+			break wait
+		case <-r.Context().Done(): // The client gave up waiting (e.g. the MCP tool call was cancelled)
+			if !s.closePrompt(promptID, "cancelled") {
+				continue // An answer raced the cancellation; pick it up on the next iteration
+			}
+			log.Info("Prompt cancelled by the client", "error", r.Context().Err())
+			resp.Error = "Prompt cancelled"
+			outcome = outcomeCancelled
+			s.webhook.send(WebhookEvent{Event: promptEventCancelled, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Error: resp.Error})
+			break wait
+		case <-s.done:
+			if !s.closePrompt(promptID, "server shutting down") {
+				continue // Answered just before shutdown; pick it up on the next iteration
+			}
+			log.Info("Server shutting down, failing prompt")
+			resp.Error = "user-prompt-server is shutting down"
+			resp.Code = protocol.ErrorCodeShuttingDown
+			outcome = outcomeShuttingDown
+			w.WriteHeader(http.StatusServiceUnavailable)
+			s.webhook.send(WebhookEvent{Event: promptEventCancelled, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Error: resp.Error})
+			break wait
+		case <-noUICh:
+			if s.clients.count() > 0 {
+				log.Info("UI client connected during the grace period")
+				continue
+			}
+			if !s.closePrompt(promptID, "no UI connected") {
+				continue // Answered via the API in the meantime; pick it up on the next iteration
+			}
+			log.Info("No UI client connected during the grace period, failing prompt", "grace", noUIGrace)
+			resp.Error = fmt.Sprintf("No UI client is connected to user-prompt-server (waited %v). Open the Vibeframe panel or /vibeframe in a browser and try again.", noUIGrace)
+			resp.Code = protocol.ErrorCodeNoUIConnected
+			outcome = outcomeNoUI
+			w.WriteHeader(http.StatusServiceUnavailable)
+			s.webhook.send(WebhookEvent{Event: promptEventCancelled, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Error: resp.Error})
+			break wait
+		case <-remindCh:
+			log.Info("Prompt still unanswered, sending reminder")
+			span.AddEvent("prompt.reminder")
+			s.broadcastSSEMessage(reminderEventData(req.Prompt, req.Title))
+			s.notifier.notify(notifyEventReminder, req.Title, req.Prompt)
+		case <-timeout.C:
+			if !s.closePrompt(promptID, "timeout") {
+				continue // An answer raced the timeout; pick it up on the next iteration
+			}
+			log.Info("Prompt timed out", "timeout", timeoutDuration)
+			resp.Error = "Prompt timed out"
+			outcome = outcomeTimedOut
+			w.WriteHeader(http.StatusGatewayTimeout) // Or another appropriate error
+			s.webhook.send(WebhookEvent{Event: promptEventTimedOut, PromptID: promptID, Title: req.Title, Prompt: req.Prompt, Error: resp.Error})
+			break wait
+		}
+	}
+
+	s.metrics.promptFinished(outcome, time.Since(requestedAt))
+	span.SetAttributes(attribute.String("prompt.outcome", outcome))
+	if outcome != outcomeAnswered {
+		span.SetStatus(codes.Error, resp.Error)
+	}
+
+	// Mark prompt as inactive after handling, regardless of outcome. Once a
+	// prompt has been closed, the next one may already have replaced it.
+	s.prompt.Lock()
+	s.prompt.lastActive = time.Now()
+	if s.prompt.details != nil && s.prompt.details.ID == promptID {
+		s.prompt.details.IsActive = false
+		presence := s.prompt.details.presenceLocked(time.Now(), s.clients.count())
+		resp.Presence = &presence
+		span.SetAttributes(
+			attribute.Bool("prompt.delivered", presence.Delivered),
+			attribute.Bool("prompt.viewed", presence.Viewed),
+			attribute.Int("ui.clients", presence.UIClients),
+		)
+	}
+	s.prompt.Unlock()
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// closePrompt marks the prompt inactive and tells the UI why. It returns
+// false if the prompt is no longer active, i.e. it has just been answered.
+func (s *Server) closePrompt(promptID, reason string) bool {
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+
+	if s.prompt.details == nil || s.prompt.details.ID != promptID || !s.prompt.details.IsActive {
+		return false
+	}
+	s.prompt.details.IsActive = false
+	s.broadcastSSEMessage(closeEventData(promptID, reason))
+	return true
+}
+
+// tracer returns the tracer for the server's spans.
+func (s *Server) tracer() trace.Tracer {
+	if s.opts.TracerProvider != nil {
+		return s.opts.TracerProvider.Tracer(tracerName)
+	}
+	return otel.Tracer(tracerName)
+}
+
+// propagator returns the propagator reading the client's trace context.
+func (s *Server) propagator() propagation.TextMapPropagator {
+	if s.opts.Propagator != nil {
+		return s.opts.Propagator
+	}
+	return otel.GetTextMapPropagator()
+}
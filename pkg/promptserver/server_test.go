@@ -0,0 +1,571 @@
+package promptserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestServer(t *testing.T, opts Options) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		ts.Close()
+	})
+	return s, ts
+}
+
+type triggerResult struct {
+	status int
+	resp   protocol.TriggerPromptResponse
+}
+
+// trigger sends req to /api/trigger-prompt in the background.
+func trigger(t *testing.T, ts *httptest.Server, req protocol.TriggerPromptRequest) <-chan triggerResult {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	result := make(chan triggerResult, 1)
+	go func() {
+		httpResp, err := http.Post(ts.URL+"/api/trigger-prompt", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Errorf("Trigger request failed: %v", err)
+			result <- triggerResult{}
+			return
+		}
+		defer httpResp.Body.Close()
+		var resp protocol.TriggerPromptResponse
+		json.NewDecoder(httpResp.Body).Decode(&resp)
+		result <- triggerResult{status: httpResp.StatusCode, resp: resp}
+	}()
+	return result
+}
+
+func waitResult(t *testing.T, result <-chan triggerResult) triggerResult {
+	t.Helper()
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the trigger response")
+		return triggerResult{}
+	}
+}
+
+// waitActive waits until the prompt with the given ID is shown.
+func waitActive(t *testing.T, ts *httptest.Server, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(ts.URL + "/api/prompts/" + id + "/status")
+		if err == nil {
+			var status protocol.PromptStatus
+			json.NewDecoder(resp.Body).Decode(&status)
+			resp.Body.Close()
+			if status.Active {
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Prompt %s never became active", id)
+}
+
+// promptStatus fetches /api/prompts/{id}/status.
+func promptStatus(t *testing.T, ts *httptest.Server, id string) protocol.PromptStatus {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/prompts/" + id + "/status")
+	if err != nil {
+		t.Fatalf("Status request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 for prompt %s, got %d", id, resp.StatusCode)
+	}
+	var status protocol.PromptStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode the status: %v", err)
+	}
+	return status
+}
+
+type sseEvent struct {
+	id   string
+	data string
+}
+
+// sseStream reads events from /events.
+type sseStream struct {
+	t      *testing.T
+	reader *bufio.Reader
+}
+
+func openSSE(t *testing.T, ts *httptest.Server, lastEventID string) *sseStream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect to /events: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return &sseStream{t: t, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the next event, skipping comments and retry fields.
+func (s *sseStream) next() sseEvent {
+	s.t.Helper()
+	var event sseEvent
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			s.t.Fatalf("Failed to read SSE stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event.data != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestTriggerAndSubmit(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", Title: "Test", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+
+	resp, err := http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "yes", "prompt_id": "p1"}`))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected submit status 200, got %d", resp.StatusCode)
+	}
+
+	r := waitResult(t, result)
+	if r.status != http.StatusOK || r.resp.Input != "yes" {
+		t.Errorf("Expected answer 'yes' with status 200, got %q with status %d", r.resp.Input, r.status)
+	}
+
+	// The prompt can only be answered once
+	resp, err = http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "again", "prompt_id": "p1"}`))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 for a second answer, got %d", resp.StatusCode)
+	}
+}
+
+func TestTriggerTimeout(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+
+	r := waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Anyone?", TimeoutMs: 50}))
+	if r.status != http.StatusGatewayTimeout || r.resp.Error != "Prompt timed out" {
+		t.Errorf("Expected timeout with status 504, got %q with status %d", r.resp.Error, r.status)
+	}
+}
+
+func TestTriggerConflict(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+
+	first := trigger(t, ts, protocol.TriggerPromptRequest{ID: "first", Prompt: "First", TimeoutMs: 5000})
+	waitActive(t, ts, "first")
+
+	r := waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Second", TimeoutMs: 5000}))
+	if r.status != http.StatusConflict || r.resp.Code != protocol.ErrorCodePromptConflict {
+		t.Errorf("Expected conflict with status 409, got code %q with status %d", r.resp.Code, r.status)
+	}
+
+	http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "done"}`))
+	waitResult(t, first)
+}
+
+func TestSSEDeliversPromptAndClose(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	stream := openSSE(t, ts, "")
+
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Shown?", Title: "SSE", TimeoutMs: 100})
+
+	prompt := stream.next()
+	if prompt.id != "1" || !strings.Contains(prompt.data, `"type":"prompt"`) || !strings.Contains(prompt.data, `"id":"p1"`) {
+		t.Errorf("Expected prompt event 1 for p1, got id %s: %s", prompt.id, prompt.data)
+	}
+	closed := stream.next()
+	if closed.id != "2" || !strings.Contains(closed.data, `"type":"close"`) || !strings.Contains(closed.data, `"reason":"timeout"`) {
+		t.Errorf("Expected close event 2 for the timeout, got id %s: %s", closed.id, closed.data)
+	}
+
+	if r := waitResult(t, result); r.resp.Presence == nil || r.resp.Presence.UIClients != 1 {
+		t.Errorf("Expected presence with 1 UI client, got %+v", r.resp.Presence)
+	}
+}
+
+func TestSSEReplaysMissedEvents(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+
+	// Events 1 and 2: a prompt that times out while no UI is connected
+	waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{ID: "missed", Prompt: "Missed", TimeoutMs: 10}))
+	// Event 3: a prompt that is still active
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "active", Prompt: "Active", TimeoutMs: 5000})
+	waitActive(t, ts, "active")
+
+	stream := openSSE(t, ts, "1")
+	for _, want := range []string{"2", "3"} {
+		if event := stream.next(); event.id != want {
+			t.Errorf("Expected replayed event %s, got %s: %s", want, event.id, event.data)
+		}
+	}
+
+	// Without a usable Last-Event-ID a client gets the active prompt
+	fresh := openSSE(t, ts, "999")
+	if event := fresh.next(); event.id != "3" || !strings.Contains(event.data, `"id":"active"`) {
+		t.Errorf("Expected the active prompt, got id %s: %s", event.id, event.data)
+	}
+
+	http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "done"}`))
+	waitResult(t, result)
+}
+
+func TestNoUIPolicyFail(t *testing.T) {
+	opts := DefaultOptions()
+	opts.NoUIPolicy = NoUIPolicyFail
+	_, ts := newTestServer(t, opts)
+
+	r := waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Nobody here", TimeoutMs: 5000}))
+	if r.status != http.StatusServiceUnavailable || r.resp.Code != protocol.ErrorCodeNoUIConnected {
+		t.Errorf("Expected no_ui_connected with status 503, got code %q with status %d", r.resp.Code, r.status)
+	}
+
+	// A connected UI keeps the prompt waiting
+	openSSE(t, ts, "")
+	r = waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Someone here", TimeoutMs: 100}))
+	if r.status != http.StatusGatewayTimeout {
+		t.Errorf("Expected the prompt to time out with a UI connected, got status %d: %s", r.status, r.resp.Error)
+	}
+}
+
+func TestAnswerAPI(t *testing.T) {
+	opts := DefaultOptions()
+	opts.APIToken = "secret"
+	_, ts := newTestServer(t, opts)
+
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Via API?", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+
+	answer := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/prompts/p1/answer", strings.NewReader(`{"input": "from API"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Answer request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := answer("wrong"); status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong token, got %d", status)
+	}
+	if status := answer("secret"); status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if r := waitResult(t, result); r.resp.Input != "from API" {
+		t.Errorf("Expected answer 'from API', got %q (%s)", r.resp.Input, r.resp.Error)
+	}
+}
+
+func TestShutdownFailsPendingPrompt(t *testing.T) {
+	s, ts := newTestServer(t, DefaultOptions())
+
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Pending", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	r := waitResult(t, result)
+	if r.status != http.StatusServiceUnavailable || r.resp.Code != protocol.ErrorCodeShuttingDown {
+		t.Errorf("Expected shutting_down with status 503, got code %q with status %d", r.resp.Code, r.status)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	s, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "user-prompt.sock")
+
+	// A stale socket from a server that did not shut down is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create a stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := Listen("unix://"+path, DefaultSocketMode)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve(l, "", "")
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Socket file missing: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != DefaultSocketMode {
+		t.Errorf("Expected socket mode %o, got %o", DefaultSocketMode, mode)
+	}
+	if _, err := Listen("unix:"+path, DefaultSocketMode); err == nil {
+		t.Error("Expected an error listening on a socket in use")
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/api/clients")
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdleAfterLastPrompt(t *testing.T) {
+	opts := DefaultOptions()
+	opts.IdleShutdown = 200 * time.Millisecond
+	s, ts := newTestServer(t, opts)
+
+	// A prompt that stays open longer than the idle period keeps the server busy
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Busy", TimeoutMs: 400})
+	waitActive(t, ts, "p1")
+	started := time.Now()
+	waitResult(t, result)
+
+	select {
+	case <-s.Idle():
+		if elapsed := time.Since(started); elapsed < 400*time.Millisecond {
+			t.Errorf("Server became idle %v after the prompt was shown, before the prompt ended", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server never became idle")
+	}
+
+	resp, err := http.Get(ts.URL + "/api/health")
+	if err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	var health HealthResponse
+	json.NewDecoder(resp.Body).Decode(&health)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || health.Status != "ok" || health.PID != os.Getpid() {
+		t.Errorf("Expected a healthy server with our PID, got status %d: %+v", resp.StatusCode, health)
+	}
+}
+
+// getMetrics returns the server's /metrics page.
+func getMetrics(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body := new(bytes.Buffer)
+	body.ReadFrom(resp.Body)
+	return body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	openSSE(t, ts, "")
+
+	waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Anyone?", TimeoutMs: 10}))
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: 5000, Queued: 2})
+	waitActive(t, ts, "p1")
+	for _, want := range []string{"user_prompt_active_prompts 1", "user_prompt_queued_prompts 2"} {
+		if body := getMetrics(t, ts); !strings.Contains(body, want+"\n") {
+			t.Errorf("Expected metrics of the active prompt to contain %q, got:\n%s", want, body)
+		}
+	}
+	waitResult(t, trigger(t, ts, protocol.TriggerPromptRequest{Prompt: "Me too", TimeoutMs: 5000}))
+	http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "yes"}`))
+	waitResult(t, result)
+
+	body := getMetrics(t, ts)
+	for _, want := range []string{
+		"user_prompt_prompts_triggered_total 3",
+		`user_prompt_prompts_total{outcome="answered"} 1`,
+		`user_prompt_prompts_total{outcome="timed_out"} 1`,
+		`user_prompt_prompts_total{outcome="conflict"} 1`,
+		`user_prompt_wait_seconds_bucket{outcome="answered",le="+Inf"} 1`,
+		`user_prompt_wait_seconds_count{outcome="timed_out"} 1`,
+		"user_prompt_active_prompts 0",
+		"user_prompt_queued_prompts 0",
+		`user_prompt_ui_clients{transport="sse"} 1`,
+		"user_prompt_dropped_broadcasts_total 0",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestTraceContextContinuesClientTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	opts := DefaultOptions()
+	opts.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	opts.Propagator = propagation.TraceContext{}
+	_, ts := newTestServer(t, opts)
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/trigger-prompt", strings.NewReader(`{"id": "traced", "prompt": "Anyone?", "timeout_ms": 50}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Trigger request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// The span ends after the handler has written the response
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exporter.GetSpans()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.SpanContext.TraceID().String() != traceID || span.Parent.SpanID().String() != parentID {
+		t.Errorf("Expected the span to continue trace %s under %s, got trace %s under %s",
+			traceID, parentID, span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+	attrs := attribute.NewSet(span.Attributes...)
+	if v, _ := attrs.Value("prompt.id"); v.AsString() != "traced" {
+		t.Errorf("Expected prompt.id traced, got %q", v.AsString())
+	}
+	if v, _ := attrs.Value("prompt.outcome"); v.AsString() != outcomeTimedOut {
+		t.Errorf("Expected prompt.outcome %s, got %q", outcomeTimedOut, v.AsString())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected an error status for a timed out prompt, got %v", span.Status.Code)
+	}
+}
+
+// pausingHandler is a slog.Handler that blocks the goroutine logging the
+// given message until release is closed, to interleave handlers in tests.
+type pausingHandler struct {
+	slog.Handler
+	message string
+	paused  chan struct{}
+	release chan struct{}
+}
+
+func (h *pausingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == h.message {
+		close(h.paused)
+		<-h.release
+	}
+	return nil
+}
+
+func (h *pausingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *pausingHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+
+func TestCancelledPromptDoesNotCloseNextPrompt(t *testing.T) {
+	pause := &pausingHandler{
+		Handler: slog.DiscardHandler,
+		message: "Prompt cancelled by the client",
+		paused:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	opts := DefaultOptions()
+	opts.Logger = slog.New(pause)
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	handlerDone := make(chan struct{}, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Handler().ServeHTTP(w, r)
+		if r.URL.Path == "/api/trigger-prompt" {
+			handlerDone <- struct{}{}
+		}
+	}))
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		ts.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body, _ := json.Marshal(protocol.TriggerPromptRequest{ID: "cancelled", Prompt: "First", TimeoutMs: 5000})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/api/trigger-prompt", bytes.NewReader(body))
+	go func() {
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	waitActive(t, ts, "cancelled")
+	cancel()
+
+	// The client sends the next prompt as soon as it sees the cancellation,
+	// before the cancelled prompt's handler has finished
+	<-pause.paused
+	next := trigger(t, ts, protocol.TriggerPromptRequest{ID: "next", Prompt: "Second", TimeoutMs: 5000})
+	waitActive(t, ts, "next")
+	close(pause.release)
+	<-handlerDone
+
+	resp, err := http.Post(ts.URL+"/submit-input", "application/json", strings.NewReader(`{"input": "yes", "prompt_id": "next"}`))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the next prompt to accept its answer, got status %d", resp.StatusCode)
+	}
+	if r := waitResult(t, next); r.resp.Input != "yes" {
+		t.Errorf("Expected the next prompt to be answered, got %q with status %d", r.resp.Input, r.status)
+	}
+}
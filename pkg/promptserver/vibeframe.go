@@ -0,0 +1,304 @@
+package promptserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// vibeframeHTML is the prompt UI served at /vibeframe. It receives prompts
+// over /ws, or /events where WebSockets are unavailable.
+const vibeframeHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>User Prompt</title>
+    <style>
+        body { font-family: sans-serif; margin: 20px; background-color: #2e2e2e; color: #d4d4d4; }
+        .container { max-width: 500px; margin: auto; padding: 20px; background-color: #3c3c3c; border-radius: 8px; box-shadow: 0 0 10px rgba(0,0,0,0.5); }
+        h2 { color: #569cd6; }
+        label { display: block; margin-bottom: 8px; }
+        input[type="text"], textarea { width: calc(100% - 22px); padding: 10px; margin-bottom: 20px; border-radius: 4px; border: 1px solid #555; background-color: #252526; color: #d4d4d4; box-sizing: border-box; }
+        textarea { min-height: 80px; }
+        button { padding: 10px 15px; border: none; border-radius: 4px; background-color: #0e639c; color: white; cursor: pointer; }
+        button:hover { background-color: #1177bb; }
+        button.secondary { background-color: #555; margin-left: 8px; }
+        button.secondary:hover { background-color: #666; }
+        body[data-theme="light"] { background-color: #f3f3f3; color: #1e1e1e; }
+        body[data-theme="light"] .container { background-color: #ffffff; box-shadow: 0 0 10px rgba(0,0,0,0.15); }
+        body[data-theme="light"] h2 { color: #0e639c; }
+        body[data-theme="light"] input[type="text"], body[data-theme="light"] textarea { background-color: #ffffff; color: #1e1e1e; border-color: #ccc; }
+        body[data-theme="light"] button.secondary { background-color: #999; }
+        #promptText { margin-bottom: 15px; white-space: pre-wrap; }
+        #inputForm { display: none; } /* Hidden initially */
+    </style>
+</head>
+<body data-theme="{{THEME}}" data-notifications="{{NOTIFICATIONS}}">
+    <div class="container">
+        <h2 id="promptTitle"></h2>
+        <p id="promptText">Waiting for LLM prompt...</p>
+        <form id="inputForm">
+            <label for="userInput">Your input:</label>
+            <textarea id="userInput" name="userInput" required></textarea>
+            <button type="submit">Submit</button>
+            <button type="button" id="dismissButton" class="secondary" style="display: none;">Dismiss</button>
+        </form>
+    </div>
+    <script>
+        const promptTitleElement = document.getElementById('promptTitle');
+        const promptTextElement = document.getElementById('promptText');
+        const inputForm = document.getElementById('inputForm');
+        const userInputElement = document.getElementById('userInput');
+        const dismissButton = document.getElementById('dismissButton');
+        let currentPromptId = '';
+        let viewedReported = false;
+        let lastTypingReport = 0;
+
+        // Presence events tell the waiting agent whether the user has seen the prompt.
+        function reportPresence(event) {
+            if (!currentPromptId) {
+                return;
+            }
+            if (event === 'typing' ? sendMessage({ type: 'typing', prompt_id: currentPromptId })
+                                   : sendMessage({ type: 'presence', prompt_id: currentPromptId, state: event })) {
+                return;
+            }
+            fetch('/api/presence', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ prompt_id: currentPromptId, event: event })
+            }).catch(error => console.error('Error reporting presence:', error));
+        }
+        function reportViewedIfVisible() {
+            if (currentPromptId && !viewedReported && document.visibilityState === 'visible') {
+                viewedReported = true;
+                reportPresence('viewed');
+            }
+        }
+        document.addEventListener('visibilitychange', reportViewedIfVisible);
+        window.addEventListener('focus', reportViewedIfVisible);
+        userInputElement.addEventListener('input', function() {
+            const now = Date.now();
+            if (now - lastTypingReport > 2000) {
+                lastTypingReport = now;
+                reportPresence('typing');
+            }
+        });
+
+        userInputElement.addEventListener('keydown', function(event) {
+            if (event.key === 'Enter' && !event.shiftKey) {
+                event.preventDefault(); // Prevent new line
+                // Find the submit button within the form and click it
+                const submitButton = inputForm.querySelector('button[type="submit"]');
+                if (submitButton) {
+                    submitButton.click();
+                }
+            }
+        });
+
+        if (document.body.dataset.theme === 'auto') {
+            const prefersLight = window.matchMedia && window.matchMedia('(prefers-color-scheme: light)').matches;
+            document.body.dataset.theme = prefersLight ? 'light' : 'dark';
+        }
+
+        // Browser notifications are shown only while the page is in the background.
+        // Permission can only be requested from a user gesture in most browsers.
+        const notificationsSupported = 'Notification' in window && document.body.dataset.notifications === 'true';
+        function requestNotificationPermission() {
+            if (notificationsSupported && Notification.permission === 'default') {
+                Notification.requestPermission();
+            }
+        }
+        document.addEventListener('click', requestNotificationPermission, { once: true });
+        document.addEventListener('keydown', requestNotificationPermission, { once: true });
+
+        function notifyUser(title, body) {
+            if (!notificationsSupported || Notification.permission !== 'granted') {
+                return;
+            }
+            if (!document.hidden && document.hasFocus()) {
+                return;
+            }
+            const notification = new Notification(title, { body: body, tag: 'user-prompt', renotify: true });
+            notification.onclick = function() {
+                window.focus();
+                userInputElement.focus();
+                notification.close();
+            };
+        }
+
+        function handleEvent(data) {
+            if (data.type === 'prompt') {
+                currentPromptId = data.id || '';
+                viewedReported = false;
+                lastTypingReport = 0;
+                promptTitleElement.textContent = data.title || 'User Input Required';
+                promptTextElement.textContent = data.prompt || 'Please provide input:';
+                userInputElement.value = '';
+                inputForm.style.display = 'block';
+                userInputElement.focus();
+                notifyUser(data.title || 'User Input Required', data.prompt || '');
+                reportViewedIfVisible();
+            } else if (data.type === 'reminder') {
+                notifyUser('Reminder: ' + (data.title || 'User Input Required'), data.prompt || '');
+            } else if (data.type === 'close') {
+                promptTitleElement.textContent = 'Prompt Closed';
+                promptTextElement.textContent = 'The prompt has been closed or timed out by the server: ' + (data.reason || '');
+                inputForm.style.display = 'none';
+            }
+        }
+
+        // The WebSocket (/ws) carries prompts, answers and presence both ways with
+        // acknowledgements. Pages fall back to SSE (/events) if it is unavailable.
+        const wsProtocolVersion = 1;
+        let socket = null;
+        let messageSeq = 0;
+        const pendingAcks = {};
+
+        function sendMessage(message, onAck) {
+            if (!socket || socket.readyState !== WebSocket.OPEN) {
+                return false;
+            }
+            message.v = wsProtocolVersion;
+            message.seq = ++messageSeq;
+            if (onAck) {
+                pendingAcks[message.seq] = onAck;
+            }
+            socket.send(JSON.stringify(message));
+            return true;
+        }
+
+        function connectWebSocket() {
+            const wsURL = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/ws';
+            let opened = false;
+            try {
+                socket = new WebSocket(wsURL);
+            } catch (err) {
+                console.error("WebSocket unavailable, falling back to SSE:", err);
+                connectEventSource();
+                return;
+            }
+            socket.onopen = function() {
+                opened = true;
+                dismissButton.style.display = 'inline-block';
+            };
+            socket.onmessage = function(event) {
+                const message = JSON.parse(event.data);
+                if (message.v !== wsProtocolVersion) {
+                    console.error("Unsupported WebSocket protocol version:", message.v);
+                    return;
+                }
+                if (message.type === 'ack') {
+                    const onAck = pendingAcks[message.ack_seq];
+                    delete pendingAcks[message.ack_seq];
+                    if (onAck) {
+                        onAck(message.error || '');
+                    }
+                    return;
+                }
+                if (message.type === 'prompt') {
+                    sendMessage({ type: 'ack', ack_seq: message.seq, prompt_id: message.prompt_id });
+                }
+                handleEvent({
+                    type: message.type === 'cancel' ? 'close' : message.type,
+                    id: message.prompt_id,
+                    prompt: message.prompt,
+                    title: message.title,
+                    reason: message.reason
+                });
+            };
+            socket.onclose = function() {
+                socket = null;
+                dismissButton.style.display = 'none';
+                for (const seq in pendingAcks) {
+                    pendingAcks[seq]('connection lost');
+                    delete pendingAcks[seq];
+                }
+                if (opened) {
+                    promptTextElement.textContent = "Connection to prompt server lost, reconnecting...";
+                    setTimeout(connectWebSocket, 2000);
+                } else {
+                    console.error("WebSocket connection failed, falling back to SSE");
+                    connectEventSource();
+                }
+            };
+        }
+
+        function connectEventSource() {
+            const eventSource = new EventSource('/events');
+            eventSource.onmessage = function(event) {
+                handleEvent(JSON.parse(event.data));
+            };
+            eventSource.onerror = function(err) {
+                console.error("EventSource failed:", err);
+                promptTextElement.textContent = "Error connecting to prompt server. Please try reloading Vibeframe or ensure the prompt server is running.";
+                // Consider not closing eventSource to allow auto-reconnect if server comes back
+            };
+        }
+
+        if ('WebSocket' in window) {
+            connectWebSocket();
+        } else {
+            connectEventSource();
+        }
+
+        function showSubmitResult(error) {
+            if (error) {
+                promptTextElement.textContent = "Input submission failed: " + error;
+                return;
+            }
+            promptTitleElement.textContent = "Input submitted.";
+            promptTextElement.textContent = "Waiting for processing...";
+        }
+
+        dismissButton.addEventListener('click', function() {
+            sendMessage({ type: 'cancel', prompt_id: currentPromptId, reason: 'dismissed in Vibeframe' }, function(error) {
+                if (error) {
+                    promptTextElement.textContent = "Dismissing the prompt failed: " + error;
+                }
+            });
+        });
+
+        inputForm.addEventListener('submit', function(e) {
+            e.preventDefault();
+            const input = userInputElement.value;
+            if (sendMessage({ type: 'answer', prompt_id: currentPromptId, input: input }, showSubmitResult)) {
+                return;
+            }
+            fetch('/submit-input', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ input: input, prompt_id: currentPromptId })
+            })
+            .then(response => {
+                if (!response.ok) {
+                    response.text().then(text => {
+                        promptTextElement.textContent = "Input submission failed: " + text;
+                        // Keep form visible for retry or show error message
+                    });
+                } else {
+                     promptTitleElement.textContent = "Input submitted.";
+                     promptTextElement.textContent = "Waiting for processing...";
+                     // The main application (client) will close the prompt or issue a new one.
+                }
+                // Do not hide form immediately, server response or new prompt will dictate UI
+            })
+            .catch(error => {
+                console.error('Error submitting input:', error);
+                promptTextElement.textContent = "Error submitting input: " + error;
+            });
+        });
+    </script>
+</body>
+</html>`
+
+func (s *Server) vibeframeHandler(w http.ResponseWriter, r *http.Request) {
+	s.log.Debug("Serving the Vibeframe page", "remote_addr", r.RemoteAddr)
+	page := strings.NewReplacer(
+		"{{THEME}}", s.opts.UITheme, // Validated by New
+		"{{NOTIFICATIONS}}", strconv.FormatBool(s.opts.UIBrowserNotifications),
+	).Replace(vibeframeHTML)
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline'; script-src 'self' 'unsafe-inline'; connect-src 'self' ws: wss:;")
+	w.Write([]byte(page))
+}
@@ -0,0 +1,209 @@
+package promptserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prompt lifecycle events delivered to the webhook.
+const (
+	promptEventCreated   = "prompt.created"
+	promptEventAnswered  = "prompt.answered"
+	promptEventTimedOut  = "prompt.timed_out"
+	promptEventCancelled = "prompt.cancelled"
+)
+
+const (
+	// webhookQueueSize is the number of events buffered for delivery.
+	webhookQueueSize = 100
+	// webhookRequestTimeout bounds a single delivery attempt.
+	webhookRequestTimeout = 10 * time.Second
+	// webhookInitialBackoff is the delay before the first retry; it doubles
+	// after every failed attempt.
+	webhookInitialBackoff = time.Second
+)
+
+// WebhookEvent is the JSON payload POSTed to the webhook URL.
+type WebhookEvent struct {
+	Event     string    `json:"event"`
+	PromptID  string    `json:"prompt_id"`
+	Title     string    `json:"title,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`
+	Input     string    `json:"input,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// webhookSink delivers prompt lifecycle events to an HTTP endpoint.
+// Events are delivered in order by a single worker goroutine.
+type webhookSink struct {
+	url        string
+	secret     string
+	maxRetries int
+	backoff    time.Duration // Before the first retry
+	client     *http.Client
+	queue      chan WebhookEvent
+	done       chan struct{} // Closed when the worker has delivered the queue
+	log        *slog.Logger
+	// ctx is cancelled when close gives up waiting; pending deliveries and
+	// retries are then abandoned.
+	ctx  context.Context
+	stop context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newWebhookSink(url, secret string, maxRetries int, logger *slog.Logger) *webhookSink {
+	ctx, stop := context.WithCancel(context.Background())
+	sink := &webhookSink{
+		url:        url,
+		secret:     secret,
+		maxRetries: maxRetries,
+		backoff:    webhookInitialBackoff,
+		client:     &http.Client{Timeout: webhookRequestTimeout},
+		queue:      make(chan WebhookEvent, webhookQueueSize),
+		done:       make(chan struct{}),
+		log:        logger,
+		ctx:        ctx,
+		stop:       stop,
+	}
+	go sink.run()
+	return sink
+}
+
+// send queues an event for delivery. It never blocks; events are dropped
+// when the queue is full. It is a no-op on a nil sink.
+func (s *webhookSink) send(event WebhookEvent) {
+	if s == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.log.Warn("Webhook is closed, dropping event", "event", event.Event, "prompt_id", event.PromptID)
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		s.log.Warn("Webhook queue is full, dropping event", "event", event.Event, "prompt_id", event.PromptID)
+	}
+}
+
+func (s *webhookSink) run() {
+	defer close(s.done)
+	for event := range s.queue {
+		s.deliver(event)
+	}
+}
+
+// close stops accepting events and waits until the queued ones are
+// delivered or ctx is done, in which case the remaining events are dropped.
+// It is a no-op on a nil sink.
+func (s *webhookSink) close(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		s.stop()
+		return fmt.Errorf("dropped pending webhook events: %w", ctx.Err())
+	}
+}
+
+// deliver POSTs the event, retrying with exponential backoff on network
+// errors, 5xx and 429 responses, until the sink is stopped.
+func (s *webhookSink) deliver(event WebhookEvent) {
+	if s.ctx.Err() != nil {
+		s.log.Warn("Webhook stopped, dropping event", "event", event.Event, "prompt_id", event.PromptID)
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.log.Error("Failed to marshal webhook event", "event", event.Event, "error", err)
+		return
+	}
+	deliveryID := uuid.NewString()
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(event.Event, deliveryID, body)
+		if err == nil {
+			s.log.Debug("Delivered webhook event", "event", event.Event, "prompt_id", event.PromptID)
+			return
+		}
+		if !retry || attempt >= s.maxRetries {
+			s.log.Error("Giving up on webhook event", "event", event.Event, "prompt_id", event.PromptID, "attempts", attempt+1, "error", err)
+			return
+		}
+		s.log.Warn("Webhook delivery failed, retrying", "event", event.Event, "prompt_id", event.PromptID, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			s.log.Warn("Webhook stopped, dropping event", "event", event.Event, "prompt_id", event.PromptID)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post performs a single delivery attempt and reports whether a failure
+// is worth retrying.
+func (s *webhookSink) post(event, deliveryID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Prompt-Event", event)
+	req.Header.Set("X-User-Prompt-Delivery", deliveryID)
+	if s.secret != "" {
+		req.Header.Set("X-User-Prompt-Signature", "sha256="+signWebhookBody(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook returned status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+}
+
+// signWebhookBody returns the hex-encoded HMAC-SHA256 of body.
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
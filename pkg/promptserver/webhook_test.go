@@ -0,0 +1,202 @@
+package promptserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookDelivery is a request received by a webhookReceiver.
+type webhookDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver records webhook requests and responds with the given
+// status codes in turn, then with 200.
+type webhookReceiver struct {
+	mu         sync.Mutex
+	deliveries []webhookDelivery
+	statuses   []int
+	block      chan struct{} // If set, requests wait until it is closed
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.deliveries = append(rc.deliveries, webhookDelivery{header: r.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	block := rc.block
+	rc.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *webhookReceiver) received() []webhookDelivery {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]webhookDelivery(nil), rc.deliveries...)
+}
+
+// newTestWebhook starts a receiver and a sink delivering to it, retrying
+// without a noticeable delay.
+func newTestWebhook(t *testing.T, rc *webhookReceiver, secret string, maxRetries int) *webhookSink {
+	t.Helper()
+	ts := httptest.NewServer(rc)
+	t.Cleanup(ts.Close)
+	sink := newWebhookSink(ts.URL, secret, maxRetries, slog.New(slog.DiscardHandler))
+	sink.backoff = time.Millisecond
+	return sink
+}
+
+func closeWebhook(t *testing.T, sink *webhookSink) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sink.close(ctx); err != nil {
+		t.Fatalf("Failed to deliver the queued events: %v", err)
+	}
+}
+
+func TestWebhookSignsDeliveries(t *testing.T) {
+	rc := &webhookReceiver{}
+	sink := newTestWebhook(t, rc, "s3cret", 0)
+	sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "p1", Prompt: "Continue?"})
+	closeWebhook(t, sink)
+
+	deliveries := rc.received()
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if got, want := d.header.Get("X-User-Prompt-Signature"), "sha256="+signWebhookBody("s3cret", d.body); got != want {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+	if got := d.header.Get("X-User-Prompt-Event"); got != promptEventCreated {
+		t.Errorf("Expected event header %q, got %q", promptEventCreated, got)
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(d.body, &event); err != nil {
+		t.Fatalf("Failed to decode the payload: %v", err)
+	}
+	if event.PromptID != "p1" || event.Prompt != "Continue?" || event.Timestamp.IsZero() {
+		t.Errorf("Unexpected payload %+v", event)
+	}
+}
+
+func TestWebhookUnsignedWithoutSecret(t *testing.T) {
+	rc := &webhookReceiver{}
+	sink := newTestWebhook(t, rc, "", 0)
+	sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "p1"})
+	closeWebhook(t, sink)
+
+	deliveries := rc.received()
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+	if sig := deliveries[0].header.Get("X-User-Prompt-Signature"); sig != "" {
+		t.Errorf("Expected no signature without a secret, got %q", sig)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		attempts   int
+	}{
+		{"server errors", []int{http.StatusInternalServerError, http.StatusBadGateway}, 3, 3},
+		{"rate limited", []int{http.StatusTooManyRequests}, 3, 2},
+		{"client error", []int{http.StatusBadRequest}, 3, 1},
+		{"retries exhausted", []int{500, 500, 500, 500}, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &webhookReceiver{statuses: tt.statuses}
+			sink := newTestWebhook(t, rc, "", tt.maxRetries)
+			sink.send(WebhookEvent{Event: promptEventAnswered, PromptID: "p1"})
+			closeWebhook(t, sink)
+
+			deliveries := rc.received()
+			if len(deliveries) != tt.attempts {
+				t.Fatalf("Expected %d attempts, got %d", tt.attempts, len(deliveries))
+			}
+			id := deliveries[0].header.Get("X-User-Prompt-Delivery")
+			for _, d := range deliveries[1:] {
+				if got := d.header.Get("X-User-Prompt-Delivery"); got != id {
+					t.Errorf("Expected retries to keep delivery ID %q, got %q", id, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookDropsEventsWhenQueueIsFull(t *testing.T) {
+	rc := &webhookReceiver{block: make(chan struct{})}
+	sink := newTestWebhook(t, rc, "", 0)
+
+	// The first event blocks the worker in the receiver, then the queue fills.
+	sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "in-flight"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rc.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the first delivery")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for range webhookQueueSize {
+		sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "queued"})
+	}
+	sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "dropped"})
+
+	close(rc.block)
+	closeWebhook(t, sink)
+
+	deliveries := rc.received()
+	if len(deliveries) != webhookQueueSize+1 {
+		t.Fatalf("Expected %d deliveries, got %d", webhookQueueSize+1, len(deliveries))
+	}
+	for _, d := range deliveries {
+		var event WebhookEvent
+		if err := json.Unmarshal(d.body, &event); err != nil {
+			t.Fatalf("Failed to decode the payload: %v", err)
+		}
+		if event.PromptID == "dropped" {
+			t.Fatal("Expected the event sent to a full queue to be dropped")
+		}
+	}
+}
+
+func TestWebhookCloseInterruptsRetries(t *testing.T) {
+	rc := &webhookReceiver{statuses: []int{500}}
+	sink := newTestWebhook(t, rc, "", 5)
+	sink.backoff = time.Hour
+	sink.send(WebhookEvent{Event: promptEventCreated, PromptID: "p1"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := sink.close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the drain to time out, got %v", err)
+	}
+	select {
+	case <-sink.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the worker to stop waiting for the retry")
+	}
+}
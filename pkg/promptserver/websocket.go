@@ -0,0 +1,249 @@
+package promptserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nazar256/user-prompt-mcp/internal/logging"
+)
+
+// wsProtocolVersion is the version of the /ws JSON protocol. Every message
+// carries it in "v", and messages of other versions are rejected.
+const wsProtocolVersion = 1
+
+// WebSocket message types. The server sends hello, prompt, reminder and
+// cancel; the UI sends answer, typing, presence and cancel; both send ack.
+const (
+	wsTypeHello    = "hello"
+	wsTypePrompt   = "prompt"
+	wsTypeReminder = "reminder"
+	wsTypeAnswer   = "answer"
+	wsTypeTyping   = "typing"
+	wsTypePresence = "presence"
+	wsTypeCancel   = "cancel"
+	wsTypeAck      = "ack"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsReadTimeout  = 2 * wsPingInterval
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsMessage is the envelope of every message on /ws.
+type wsMessage struct {
+	V        int    `json:"v"`
+	Type     string `json:"type"`
+	Seq      int64  `json:"seq,omitempty"`     // Sender's message number; the peer acks prompts and answers by it
+	AckSeq   int64  `json:"ack_seq,omitempty"` // For ack: the acknowledged message
+	PromptID string `json:"prompt_id,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Input    string `json:"input,omitempty"`  // For answer
+	State    string `json:"state,omitempty"`  // For presence: "viewed"
+	Reason   string `json:"reason,omitempty"` // For cancel
+	Error    string `json:"error,omitempty"`  // For ack: why the message was rejected
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// errPromptDismissed is delivered to the waiting request when the user
+// dismisses the prompt from the UI.
+var errPromptDismissed = errors.New("prompt dismissed by the user")
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The default CheckOrigin only accepts same-origin pages, like /vibeframe.
+}
+
+// websocketHandler serves /ws, a bidirectional alternative to /events and
+// /submit-input. WebSocket clients receive the same broadcasts as SSE clients
+// and count as connected UIs.
+func (s *Server) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.log.Warn("WebSocket upgrade failed", "remote_addr", r.RemoteAddr, "error", err)
+		return // Upgrade has already replied with an error
+	}
+	defer conn.Close()
+
+	client := newUIClient(r, transportWebSocket)
+	clientKey := client.ID
+	replies := make(chan wsMessage, 10)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// WebSocket clients do not replay events on reconnect; they get the
+	// active prompt instead.
+	initial := s.subscribeEvents(client, "")
+	defer s.clients.unregister(client)
+	log := s.log.With("client_id", clientKey, "transport", transportWebSocket)
+	log.Info("UI client connected", "remote_addr", r.RemoteAddr, "user_agent", r.UserAgent())
+
+	go func() {
+		defer cancel()
+		s.readWebSocket(ctx, conn, log, replies)
+	}()
+
+	var seq int64
+	send := func(msg wsMessage) error {
+		seq++
+		msg.V = wsProtocolVersion
+		msg.Seq = seq
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(msg)
+	}
+
+	if err := send(wsMessage{Type: wsTypeHello, ClientID: clientKey}); err != nil {
+		log.Warn("Failed to send hello", "error", err)
+		return
+	}
+	for _, event := range initial {
+		log.Debug("Sending initial event", "event_id", event.ID)
+		msg, err := wsMessageFromEvent(event)
+		if err == nil {
+			err = send(msg)
+		}
+		if err != nil {
+			log.Warn("Failed to send initial event", "event_id", event.ID, "error", err)
+			return
+		}
+	}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case event := <-client.events:
+			var msg wsMessage
+			if msg, err = wsMessageFromEvent(event); err != nil {
+				log.Error("Skipping undecodable event", "event_id", event.ID, "error", err)
+				continue
+			}
+			err = send(msg)
+		case <-client.lagged:
+			log.Debug("UI client fell behind, closing the connection")
+			return
+		case reply := <-replies:
+			err = send(reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		case <-s.done:
+			log.Debug("Server shutting down, closing the connection")
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(wsWriteTimeout))
+			return
+		case <-ctx.Done():
+			log.Info("UI client disconnected")
+			return
+		}
+		if err != nil {
+			log.Warn("Write failed, closing the connection", "error", err)
+			return
+		}
+	}
+}
+
+// wsMessageFromEvent converts a broadcast event to a WebSocket message.
+func wsMessageFromEvent(event uiEvent) (wsMessage, error) {
+	var data uiEventData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return wsMessage{}, err
+	}
+	if data.Type == "close" {
+		data.Type = wsTypeCancel
+	}
+	return wsMessage{Type: data.Type, PromptID: data.ID, Prompt: data.Prompt, Title: data.Title, Reason: data.Reason}, nil
+}
+
+// readWebSocket handles messages from a WebSocket client until the
+// connection fails, queueing acks on replies.
+func (s *Server) readWebSocket(ctx context.Context, conn *websocket.Conn, log *slog.Logger, replies chan<- wsMessage) {
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	})
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Warn("Read failed", "error", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+		err := s.handleWebSocketMessage(msg)
+		if err != nil {
+			log.Warn("Rejected message", "type", msg.Type, "error", err)
+		}
+		if msg.Type == wsTypeAck {
+			continue // Acks are never acknowledged
+		}
+		reply := wsMessage{Type: wsTypeAck, AckSeq: msg.Seq, PromptID: msg.PromptID}
+		if err != nil {
+			reply.Error = err.Error()
+		} else if msg.Type != wsTypeAnswer && msg.Type != wsTypeCancel {
+			continue // Only answers and cancellations need delivery confirmation
+		}
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleWebSocketMessage(msg wsMessage) error {
+	if msg.V != wsProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, expected %d", msg.V, wsProtocolVersion)
+	}
+
+	switch msg.Type {
+	case wsTypeAnswer:
+		if err := s.answerPrompt(msg.PromptID, msg.Input); err != nil {
+			return err
+		}
+		s.log.Info("Received answer via WebSocket", "prompt_id", msg.PromptID, logging.Answer(msg.Input))
+		return nil
+	case wsTypeCancel:
+		return s.dismissPrompt(msg.PromptID, msg.Reason)
+	case wsTypeTyping:
+		return s.recordPresence(msg.PromptID, presenceEventTyping)
+	case wsTypePresence:
+		return s.recordPresence(msg.PromptID, msg.State)
+	case wsTypeAck:
+		// The UI acks prompts it has displayed, which tells the waiting
+		// client the prompt was delivered.
+		return s.recordPresence(msg.PromptID, presenceEventDelivered)
+	default:
+		return fmt.Errorf("unknown message type %q", msg.Type)
+	}
+}
+
+// dismissPrompt ends the prompt with errPromptDismissed, e.g. when the user
+// closes it in the UI without answering.
+func (s *Server) dismissPrompt(promptID, reason string) error {
+	s.prompt.Lock()
+	defer s.prompt.Unlock()
+
+	details := s.prompt.details
+	if details == nil || !details.IsActive || details.ID != promptID {
+		return errPromptNotPending
+	}
+	details.IsActive = false
+	err := errPromptDismissed
+	if reason != "" {
+		err = fmt.Errorf("%w: %s", errPromptDismissed, reason)
+	}
+	details.ErrorChan <- err // Buffered, never blocks for the single error
+	s.broadcastSSEMessage(closeEventData(promptID, "dismissed by the user"))
+	return nil
+}
@@ -0,0 +1,270 @@
+package promptserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nazar256/user-prompt-mcp/internal/protocol"
+)
+
+// wsClient is a UI connected to /ws.
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+	seq  int64
+}
+
+func dialWS(t *testing.T, ts *httptest.Server) *wsClient {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to /ws: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &wsClient{t: t, conn: conn}
+	if hello := c.next(); hello.Type != wsTypeHello || hello.ClientID == "" || hello.V != wsProtocolVersion {
+		t.Fatalf("Expected hello with a client ID, got %+v", hello)
+	}
+	return c
+}
+
+// send sends msg with the current protocol version and the next sequence
+// number, which it returns.
+func (c *wsClient) send(msg wsMessage) int64 {
+	c.t.Helper()
+	c.seq++
+	msg.V, msg.Seq = wsProtocolVersion, c.seq
+	c.sendRaw(msg)
+	return c.seq
+}
+
+func (c *wsClient) sendRaw(msg wsMessage) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("Failed to send %s: %v", msg.Type, err)
+	}
+}
+
+func (c *wsClient) next() wsMessage {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg wsMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		c.t.Fatalf("Failed to read from /ws: %v", err)
+	}
+	return msg
+}
+
+// nextOf returns the next message of the given type, skipping others.
+func (c *wsClient) nextOf(msgType string) wsMessage {
+	c.t.Helper()
+	for {
+		if msg := c.next(); msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestWebSocketPromptAndAnswer(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	c := dialWS(t, ts)
+
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", Title: "WS", TimeoutMs: 5000})
+	prompt := c.next()
+	if prompt.Type != wsTypePrompt || prompt.PromptID != "p1" || prompt.Prompt != "Continue?" || prompt.Title != "WS" {
+		t.Fatalf("Expected prompt p1, got %+v", prompt)
+	}
+	if prompt.Seq != 2 {
+		t.Errorf("Expected the prompt to follow hello as seq 2, got %d", prompt.Seq)
+	}
+
+	// Acking the prompt marks it delivered; acks are not acknowledged.
+	c.send(wsMessage{Type: wsTypeAck, AckSeq: prompt.Seq, PromptID: "p1"})
+	if status := promptStatus(t, ts, "p1"); !status.Delivered {
+		t.Errorf("Expected the acked prompt to be delivered, got %+v", status)
+	}
+
+	seq := c.send(wsMessage{Type: wsTypeAnswer, PromptID: "p1", Input: "yes"})
+	ack := c.nextOf(wsTypeAck)
+	if ack.AckSeq != seq || ack.PromptID != "p1" || ack.Error != "" {
+		t.Errorf("Expected a successful ack of seq %d, got %+v", seq, ack)
+	}
+	if r := waitResult(t, result); r.status != http.StatusOK || r.resp.Input != "yes" {
+		t.Errorf("Expected answer 'yes' with status 200, got %q with status %d", r.resp.Input, r.status)
+	}
+}
+
+func TestWebSocketPromptWithControlCharacters(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	c := dialWS(t, ts)
+
+	// Control characters and invalid UTF-8 must not break the event's JSON
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Beep\a\x00\v?", Title: "Bad \xff byte", TimeoutMs: 5000})
+	prompt := c.nextOf(wsTypePrompt)
+	if prompt.PromptID != "p1" || prompt.Prompt != "Beep\a\x00\v?" || prompt.Title != "Bad \uFFFD byte" {
+		t.Fatalf("Expected prompt p1 with its control characters, got %+v", prompt)
+	}
+	c.send(wsMessage{Type: wsTypeAnswer, PromptID: "p1", Input: "yes"})
+	waitResult(t, result)
+}
+
+func TestWebSocketSendsActivePromptOnConnect(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Still there?", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+
+	c := dialWS(t, ts)
+	if prompt := c.next(); prompt.Type != wsTypePrompt || prompt.PromptID != "p1" {
+		t.Fatalf("Expected the active prompt, got %+v", prompt)
+	}
+	c.send(wsMessage{Type: wsTypeAnswer, PromptID: "p1", Input: "yes"})
+	waitResult(t, result)
+}
+
+func TestWebSocketRejectsMessages(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: 5000})
+	waitActive(t, ts, "p1")
+	c := dialWS(t, ts)
+	c.nextOf(wsTypePrompt)
+
+	tests := []struct {
+		name  string
+		msg   wsMessage
+		error string
+	}{
+		{"unsupported version", wsMessage{V: wsProtocolVersion + 1, Type: wsTypeAnswer, Seq: 10, PromptID: "p1", Input: "yes"}, "unsupported protocol version"},
+		{"stale answer", wsMessage{V: wsProtocolVersion, Type: wsTypeAnswer, Seq: 11, PromptID: "old", Input: "yes"}, errPromptNotPending.Error()},
+		{"stale cancel", wsMessage{V: wsProtocolVersion, Type: wsTypeCancel, Seq: 12, PromptID: "old"}, errPromptNotPending.Error()},
+		{"unknown presence", wsMessage{V: wsProtocolVersion, Type: wsTypePresence, Seq: 13, PromptID: "p1", State: "asleep"}, "unknown presence event"},
+		{"unknown type", wsMessage{V: wsProtocolVersion, Type: "shout", Seq: 14}, "unknown message type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.t = t
+			c.sendRaw(tt.msg)
+			ack := c.nextOf(wsTypeAck)
+			if ack.AckSeq != tt.msg.Seq || !strings.Contains(ack.Error, tt.error) {
+				t.Errorf("Expected an ack of seq %d with error %q, got %+v", tt.msg.Seq, tt.error, ack)
+			}
+		})
+	}
+	c.t = t
+
+	// None of the rejected messages answered the prompt
+	if status := promptStatus(t, ts, "p1"); !status.Active {
+		t.Fatalf("Expected p1 to still be active, got %+v", status)
+	}
+	c.send(wsMessage{Type: wsTypeAnswer, PromptID: "p1", Input: "yes"})
+	if r := waitResult(t, result); r.resp.Input != "yes" {
+		t.Errorf("Expected answer 'yes', got %+v", r.resp)
+	}
+}
+
+func TestWebSocketPresence(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	c := dialWS(t, ts)
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: 5000})
+	c.nextOf(wsTypePrompt)
+
+	c.send(wsMessage{Type: wsTypePresence, PromptID: "p1", State: presenceEventViewed})
+	c.send(wsMessage{Type: wsTypeTyping, PromptID: "p1"})
+	// Presence messages are not acknowledged, so the answer's ack is the
+	// next one and confirms that they were handled.
+	seq := c.send(wsMessage{Type: wsTypeAnswer, PromptID: "p1", Input: "yes"})
+	if ack := c.nextOf(wsTypeAck); ack.AckSeq != seq {
+		t.Fatalf("Expected the answer to be acked first, got %+v", ack)
+	}
+
+	r := waitResult(t, result)
+	if p := r.resp.Presence; p == nil || !p.Viewed || p.ViewedAt == nil || !p.Typing || !p.Delivered || p.UIClients != 1 {
+		t.Errorf("Expected a viewed, typing prompt with 1 UI client, got %+v", p)
+	}
+}
+
+func TestWebSocketDismiss(t *testing.T) {
+	_, ts := newTestServer(t, DefaultOptions())
+	c := dialWS(t, ts)
+	result := trigger(t, ts, protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: 5000})
+	c.nextOf(wsTypePrompt)
+
+	seq := c.send(wsMessage{Type: wsTypeCancel, PromptID: "p1", Reason: "not now"})
+	// The broadcast cancel may overtake the ack
+	received := map[string]wsMessage{}
+	for len(received) < 2 {
+		msg := c.next()
+		received[msg.Type] = msg
+	}
+	if ack := received[wsTypeAck]; ack.AckSeq != seq || ack.Error != "" {
+		t.Errorf("Expected a successful ack of seq %d, got %+v", seq, ack)
+	}
+	if cancel := received[wsTypeCancel]; cancel.PromptID != "p1" || cancel.Reason != "dismissed by the user" {
+		t.Errorf("Expected the prompt to be closed as dismissed, got %+v", cancel)
+	}
+
+	r := waitResult(t, result)
+	if r.status != http.StatusConflict || r.resp.Code != protocol.ErrorCodeDismissed || !strings.Contains(r.resp.Error, "not now") {
+		t.Errorf("Expected a dismissal with status 409 and the reason, got %+v with status %d", r.resp, r.status)
+	}
+}
+
+func TestWebSocketCancelsClosedPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout int64
+		cancel  bool
+		reason  string
+	}{
+		{"timeout", 100, false, "timeout"},
+		{"client cancelled", 5000, true, "cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ts := newTestServer(t, DefaultOptions())
+			c := dialWS(t, ts)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			body, _ := json.Marshal(protocol.TriggerPromptRequest{ID: "p1", Prompt: "Continue?", TimeoutMs: tt.timeout})
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/api/trigger-prompt", bytes.NewReader(body))
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				if resp, err := http.DefaultClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}()
+
+			c.nextOf(wsTypePrompt)
+			if tt.cancel {
+				cancel()
+			}
+			if msg := c.nextOf(wsTypeCancel); msg.PromptID != "p1" || msg.Reason != tt.reason {
+				t.Errorf("Expected p1 to be cancelled with reason %q, got %+v", tt.reason, msg)
+			}
+			<-done
+		})
+	}
+}
+
+func TestWebSocketClosedOnShutdown(t *testing.T) {
+	s, ts := newTestServer(t, DefaultOptions())
+	c := dialWS(t, ts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go s.Shutdown(ctx)
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := c.conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going-away close, got %v", err)
+	}
+}